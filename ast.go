@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/zzz"
+)
+
+// astCommand prints the syntax tree of a program, as an indented outline or
+// as JSON.
+func astCommand(args []string) int {
+	flags := flag.NewFlagSet("zzz ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: zzz ast [-json] file.zzz")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	program, err := zzz.ParseFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(program)
+	} else {
+		err = printOutline(os.Stdout, program)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// printOutline prints every node of program on its own line, indented by its
+// depth, with the text of its token.
+func printOutline(w io.Writer, program *ast.Program) error {
+	var err error
+	depth := 0
+	ast.Inspect(program, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+
+		kind := strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast.")
+		line := strings.Repeat("  ", depth) + kind
+		if literal := n.TokenLiteral(); literal != "" {
+			line += fmt.Sprintf(" %q", literal)
+		}
+		if _, werr := fmt.Fprintln(w, line); werr != nil && err == nil {
+			err = werr
+		}
+		depth++
+		return true
+	})
+	return err
+}
@@ -55,7 +55,7 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	out.WriteString(declaration(ls.Name))
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -84,9 +84,38 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+type ImportStatement struct {
+	Token token.Token // token.IMPORT
+	Path  *StringLiteral
+	Alias *Identifier // nil when the module is bound under its file name
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(is.TokenLiteral() + " ")
+	out.WriteString(`"` + is.Path.Value + `"`)
+	if is.Alias != nil {
+		out.WriteString(" as " + is.Alias.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token // token.IDENT
 	Value string
+	Type  *TypeAnnotation // declared type of a `lit` name or parameter, nil unless annotated
+
+	// Set by Resolve when the name is bound in an enclosing function: the
+	// binding is in slot Slot of the call frame Depth functions out. Other
+	// names are globals and looked up by Value.
+	Local bool
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expressionNode()      {}
@@ -98,6 +127,25 @@ type ExpressionStatement struct {
 	Expression Expression
 }
 
+// TypeAnnotation is an optional type written after a binding or a function's
+// parameters, like the `int` in `lit x: int = 1` or `fun(a) -> int { a }`.
+// Annotations are only read by static checks; they do not change evaluation.
+type TypeAnnotation struct {
+	Token token.Token // the type name
+	Name  string
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
+
+// declaration prints a bound name with its annotation, if any.
+func declaration(name *Identifier) string {
+	if name.Type == nil {
+		return name.String()
+	}
+	return name.String() + ": " + name.Type.String()
+}
+
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 func (es *ExpressionStatement) String() string {
@@ -166,8 +214,9 @@ func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 type BlockStatement struct {
-	Token      token.Token
+	Token      token.Token // '{' token
 	Statements []Statement
+	End        token.Token // '}' token
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -210,7 +259,9 @@ func (ie *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      token.Token // 'fn' token
 	Parameters []*Identifier
+	ReturnType *TypeAnnotation // nil unless annotated
 	Body       *BlockStatement
+	Locals     []string // names of the call frame slots, parameters first, set by Resolve
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -220,22 +271,26 @@ func (fl *FunctionLiteral) String() string {
 
 	params := []string{}
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		params = append(params, declaration(p))
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
 type CallExpression struct {
-	Token     token.Token
+	Token     token.Token // '(' token
 	Function  Expression
 	Arguments []Expression
+	End       token.Token // ')' token
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -265,9 +320,35 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// TemplateLiteral is a string with embedded expressions, as in "hi ${name}".
+// Texts holds the text around the expressions, so it has one more element
+// than Exprs: Texts[i] comes before Exprs[i].
+type TemplateLiteral struct {
+	Token token.Token // the token.TEMPLATE token
+	Texts []string
+	Exprs []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode()      {}
+func (tl *TemplateLiteral) TokenLiteral() string { return tl.Token.Literal }
+func (tl *TemplateLiteral) String() string {
+	var out bytes.Buffer
+
+	for i, expr := range tl.Exprs {
+		out.WriteString(tl.Texts[i])
+		out.WriteString("${")
+		out.WriteString(expr.String())
+		out.WriteString("}")
+	}
+	out.WriteString(tl.Texts[len(tl.Texts)-1])
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token // '[' token
 	Elements []Expression
+	End      token.Token // ']' token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
@@ -307,9 +388,64 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression is `left[start:end]`. Start and End are nil when omitted.
+type SliceExpression struct {
+	Token token.Token // '[' token
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+type MemberExpression struct {
+	Token    token.Token // '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token token.Token // '{' token
-	Pairs map[Expression]Expression
+	Pairs []HashPair  // in source order
+	End   token.Token // '}' token
+}
+
+// HashPair is a key and its value in a hash literal.
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
@@ -318,8 +454,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -27,3 +27,17 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestHashLiteralStringKeepsOrder(t *testing.T) {
+	str := func(value string) Expression {
+		return &StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+	}
+	hash := &HashLiteral{
+		Token: token.Token{Type: token.LBRACE, Literal: "{"},
+		Pairs: []HashPair{{Key: str("b"), Value: str("1")}, {Key: str("a"), Value: str("2")}},
+	}
+
+	if hash.String() != "{b: 1, a: 2}" {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
@@ -0,0 +1,484 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/amirhesham65/zzz-lang/token"
+)
+
+// Nodes marshal to JSON objects with the node's kind, its token and the
+// node's own fields, children included:
+//
+//	{"kind": "PrefixExpression",
+//	 "token": {"type": "-", "literal": "-", "line": 1, "column": 1},
+//	 "operator": "-",
+//	 "right": {"kind": "IntegerLiteral", ...}}
+//
+// Fields that are nil are left out. Hash literal pairs are a list of
+// {"key": ..., "value": ...} objects. What the resolver and
+// the optimizer record on nodes is not marshalled, since running them again
+// restores it.
+
+func (p *Program) MarshalJSON() ([]byte, error)              { return marshalNode(p) }
+func (ls *LetStatement) MarshalJSON() ([]byte, error)        { return marshalNode(ls) }
+func (rs *ReturnStatement) MarshalJSON() ([]byte, error)     { return marshalNode(rs) }
+func (is *ImportStatement) MarshalJSON() ([]byte, error)     { return marshalNode(is) }
+func (es *ExpressionStatement) MarshalJSON() ([]byte, error) { return marshalNode(es) }
+func (bs *BlockStatement) MarshalJSON() ([]byte, error)      { return marshalNode(bs) }
+func (i *Identifier) MarshalJSON() ([]byte, error)           { return marshalNode(i) }
+func (ta *TypeAnnotation) MarshalJSON() ([]byte, error)      { return marshalNode(ta) }
+func (il *IntegerLiteral) MarshalJSON() ([]byte, error)      { return marshalNode(il) }
+func (sl *StringLiteral) MarshalJSON() ([]byte, error)       { return marshalNode(sl) }
+func (b *Boolean) MarshalJSON() ([]byte, error)              { return marshalNode(b) }
+func (pe *PrefixExpression) MarshalJSON() ([]byte, error)    { return marshalNode(pe) }
+func (ie *InfixExpression) MarshalJSON() ([]byte, error)     { return marshalNode(ie) }
+func (ie *IfExpression) MarshalJSON() ([]byte, error)        { return marshalNode(ie) }
+func (fl *FunctionLiteral) MarshalJSON() ([]byte, error)     { return marshalNode(fl) }
+func (ce *CallExpression) MarshalJSON() ([]byte, error)      { return marshalNode(ce) }
+func (al *ArrayLiteral) MarshalJSON() ([]byte, error)        { return marshalNode(al) }
+func (tl *TemplateLiteral) MarshalJSON() ([]byte, error)     { return marshalNode(tl) }
+func (ie *IndexExpression) MarshalJSON() ([]byte, error)     { return marshalNode(ie) }
+func (se *SliceExpression) MarshalJSON() ([]byte, error)     { return marshalNode(se) }
+func (me *MemberExpression) MarshalJSON() ([]byte, error)    { return marshalNode(me) }
+func (hl *HashLiteral) MarshalJSON() ([]byte, error)         { return marshalNode(hl) }
+
+// UnmarshalJSON reconstructs a program marshalled by MarshalJSON.
+func (p *Program) UnmarshalJSON(data []byte) error {
+	node, err := UnmarshalNode(data)
+	if err != nil {
+		return err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return fmt.Errorf("ast: expected a Program, got %s", kindOf(node))
+	}
+	*p = *program
+	return nil
+}
+
+// UnmarshalNode reconstructs a node of any kind from its JSON form, resolved
+// by Resolve.
+func UnmarshalNode(data []byte) (Node, error) {
+	node, err := decodeNode(data)
+	if err != nil {
+		return nil, fmt.Errorf("ast: %w", err)
+	}
+	Resolve(node)
+	return node, nil
+}
+
+func decodeNode(data []byte) (Node, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	d := &decoder{obj: obj}
+	node := d.node()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+// field is a member of a JSON object.
+type field struct {
+	key   string
+	value any
+}
+
+// object is a JSON object that keeps its fields in order.
+type object []field
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line,omitempty"`
+	Column  int             `json:"column,omitempty"`
+}
+
+func (t jsonToken) token() token.Token {
+	return token.Token{Type: t.Type, Literal: t.Literal, Line: t.Line, Column: t.Column}
+}
+
+func newJSONToken(t token.Token) jsonToken {
+	return jsonToken{Type: t.Type, Literal: t.Literal, Line: t.Line, Column: t.Column}
+}
+
+// kindOf returns the name of a node's type, like "LetStatement".
+func kindOf(node Node) string {
+	return fmt.Sprintf("%T", node)[len("*ast."):]
+}
+
+func marshalNode(node Node) ([]byte, error) {
+	o := object{{"kind", kindOf(node)}}
+	add := func(key string, value any) {
+		if n, ok := value.(Node); value == nil || ok && isNil(n) {
+			return
+		}
+		o = append(o, field{key, value})
+	}
+	tok := func(t token.Token) {
+		add("token", newJSONToken(t))
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		add("statements", nonNil(node.Statements))
+	case *LetStatement:
+		tok(node.Token)
+		add("name", node.Name)
+		add("value", node.Value)
+	case *ReturnStatement:
+		tok(node.Token)
+		add("returnValue", node.ReturnValue)
+	case *ImportStatement:
+		tok(node.Token)
+		add("path", node.Path)
+		add("alias", node.Alias)
+	case *ExpressionStatement:
+		tok(node.Token)
+		add("expression", node.Expression)
+	case *BlockStatement:
+		tok(node.Token)
+		add("statements", nonNil(node.Statements))
+		add("end", newJSONToken(node.End))
+	case *Identifier:
+		tok(node.Token)
+		add("value", node.Value)
+		add("type", node.Type)
+	case *TypeAnnotation:
+		tok(node.Token)
+		add("name", node.Name)
+	case *IntegerLiteral:
+		tok(node.Token)
+		add("value", node.Value)
+	case *StringLiteral:
+		tok(node.Token)
+		add("value", node.Value)
+	case *Boolean:
+		tok(node.Token)
+		add("value", node.Value)
+	case *PrefixExpression:
+		tok(node.Token)
+		add("operator", node.Operator)
+		add("right", node.Right)
+	case *InfixExpression:
+		tok(node.Token)
+		add("operator", node.Operator)
+		add("left", node.Left)
+		add("right", node.Right)
+	case *IfExpression:
+		tok(node.Token)
+		add("condition", node.Condition)
+		add("consequence", node.Consequence)
+		add("alternative", node.Alternative)
+	case *FunctionLiteral:
+		tok(node.Token)
+		add("parameters", nonNil(node.Parameters))
+		add("returnType", node.ReturnType)
+		add("body", node.Body)
+	case *CallExpression:
+		tok(node.Token)
+		add("function", node.Function)
+		add("arguments", nonNil(node.Arguments))
+		add("end", newJSONToken(node.End))
+	case *ArrayLiteral:
+		tok(node.Token)
+		add("elements", nonNil(node.Elements))
+		add("end", newJSONToken(node.End))
+	case *TemplateLiteral:
+		tok(node.Token)
+		add("texts", nonNil(node.Texts))
+		add("exprs", nonNil(node.Exprs))
+	case *IndexExpression:
+		tok(node.Token)
+		add("left", node.Left)
+		add("index", node.Index)
+	case *SliceExpression:
+		tok(node.Token)
+		add("left", node.Left)
+		add("start", node.Start)
+		add("end", node.End)
+	case *MemberExpression:
+		tok(node.Token)
+		add("object", node.Object)
+		add("property", node.Property)
+	case *HashLiteral:
+		tok(node.Token)
+		pairs := []object{}
+		for _, pair := range node.Pairs {
+			pairs = append(pairs, object{{"key", pair.Key}, {"value", pair.Value}})
+		}
+		add("pairs", pairs)
+		add("end", newJSONToken(node.End))
+	}
+
+	return o.MarshalJSON()
+}
+
+// nonNil returns list, or an empty list instead of nil, so that lists always
+// marshal as arrays.
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
+
+// decoder reconstructs a node from its JSON object. The first error stops
+// decoding; later calls return zero values.
+type decoder struct {
+	obj map[string]json.RawMessage
+	err error
+}
+
+func (d *decoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+// value decodes the field key into v. Missing fields leave v unchanged.
+func (d *decoder) value(key string, v any) {
+	raw, ok := d.obj[key]
+	if !ok || d.err != nil {
+		return
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.fail("%s: %s", key, err)
+	}
+}
+
+func (d *decoder) token() token.Token {
+	var t jsonToken
+	d.value("token", &t)
+	return t.token()
+}
+
+// child decodes the node in field key, nil if it is missing.
+func (d *decoder) child(key string) Node {
+	raw, ok := d.obj[key]
+	if !ok || d.err != nil || string(raw) == "null" {
+		return nil
+	}
+	node, err := decodeNode(raw)
+	if err != nil {
+		d.fail("%s: %s", key, err)
+	}
+	return node
+}
+
+// require fails unless each of keys holds a node, for the fields the parser
+// always fills in.
+func (d *decoder) require(keys ...string) {
+	for _, key := range keys {
+		if raw, ok := d.obj[key]; !ok || string(raw) == "null" {
+			d.fail("%s: missing", key)
+		}
+	}
+}
+
+// children decodes the list of nodes in field key.
+func (d *decoder) children(key string) []Node {
+	var raws []json.RawMessage
+	d.value(key, &raws)
+
+	var nodes []Node
+	for i, raw := range raws {
+		node, err := decodeNode(raw)
+		if err != nil {
+			d.fail("%s[%d]: %s", key, i, err)
+			return nil
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (d *decoder) expression(key string) Expression {
+	return as[Expression](d, key, d.child(key))
+}
+
+func (d *decoder) identifier(key string) *Identifier {
+	return as[*Identifier](d, key, d.child(key))
+}
+
+func (d *decoder) block(key string) *BlockStatement {
+	return as[*BlockStatement](d, key, d.child(key))
+}
+
+func (d *decoder) annotation(key string) *TypeAnnotation {
+	return as[*TypeAnnotation](d, key, d.child(key))
+}
+
+func (d *decoder) statements(key string) []Statement {
+	stmts := []Statement{}
+	for _, node := range d.children(key) {
+		stmts = append(stmts, as[Statement](d, key, node))
+	}
+	return stmts
+}
+
+func (d *decoder) expressions(key string) []Expression {
+	exprs := []Expression{}
+	for _, node := range d.children(key) {
+		exprs = append(exprs, as[Expression](d, key, node))
+	}
+	return exprs
+}
+
+// as converts a decoded node to the type of the field it belongs in. A nil
+// node gives the zero value.
+func as[T Node](d *decoder, key string, node Node) T {
+	var zero T
+	if node == nil {
+		return zero
+	}
+	t, ok := node.(T)
+	if !ok {
+		d.fail("%s: unexpected %s", key, kindOf(node))
+		return zero
+	}
+	return t
+}
+
+func (d *decoder) node() Node {
+	var kind string
+	d.value("kind", &kind)
+
+	switch kind {
+	case "Program":
+		return &Program{Statements: d.statements("statements")}
+	case "LetStatement":
+		d.require("name", "value")
+		return &LetStatement{Token: d.token(), Name: d.identifier("name"), Value: d.expression("value")}
+	case "ReturnStatement":
+		d.require("returnValue")
+		return &ReturnStatement{Token: d.token(), ReturnValue: d.expression("returnValue")}
+	case "ImportStatement":
+		d.require("path")
+		node := &ImportStatement{Token: d.token(), Alias: d.identifier("alias")}
+		node.Path = as[*StringLiteral](d, "path", d.child("path"))
+		return node
+	case "ExpressionStatement":
+		d.require("expression")
+		return &ExpressionStatement{Token: d.token(), Expression: d.expression("expression")}
+	case "BlockStatement":
+		var end jsonToken
+		d.value("end", &end)
+		return &BlockStatement{Token: d.token(), Statements: d.statements("statements"), End: end.token()}
+	case "Identifier":
+		node := &Identifier{Token: d.token(), Type: d.annotation("type")}
+		d.value("value", &node.Value)
+		return node
+	case "TypeAnnotation":
+		node := &TypeAnnotation{Token: d.token()}
+		d.value("name", &node.Name)
+		return node
+	case "IntegerLiteral":
+		node := &IntegerLiteral{Token: d.token()}
+		d.value("value", &node.Value)
+		return node
+	case "StringLiteral":
+		node := &StringLiteral{Token: d.token()}
+		d.value("value", &node.Value)
+		return node
+	case "Boolean":
+		node := &Boolean{Token: d.token()}
+		d.value("value", &node.Value)
+		return node
+	case "PrefixExpression":
+		d.require("right")
+		node := &PrefixExpression{Token: d.token(), Right: d.expression("right")}
+		d.value("operator", &node.Operator)
+		return node
+	case "InfixExpression":
+		d.require("left", "right")
+		node := &InfixExpression{Token: d.token(), Left: d.expression("left"), Right: d.expression("right")}
+		d.value("operator", &node.Operator)
+		return node
+	case "IfExpression":
+		d.require("condition", "consequence")
+		return &IfExpression{
+			Token:       d.token(),
+			Condition:   d.expression("condition"),
+			Consequence: d.block("consequence"),
+			Alternative: d.block("alternative"),
+		}
+	case "FunctionLiteral":
+		d.require("body")
+		node := &FunctionLiteral{Token: d.token(), ReturnType: d.annotation("returnType"), Body: d.block("body")}
+		for _, param := range d.children("parameters") {
+			node.Parameters = append(node.Parameters, as[*Identifier](d, "parameters", param))
+		}
+		return node
+	case "CallExpression":
+		d.require("function")
+		var end jsonToken
+		d.value("end", &end)
+		return &CallExpression{Token: d.token(), Function: d.expression("function"), Arguments: d.expressions("arguments"), End: end.token()}
+	case "ArrayLiteral":
+		var end jsonToken
+		d.value("end", &end)
+		return &ArrayLiteral{Token: d.token(), Elements: d.expressions("elements"), End: end.token()}
+	case "TemplateLiteral":
+		node := &TemplateLiteral{Token: d.token(), Exprs: d.expressions("exprs")}
+		d.value("texts", &node.Texts)
+		if d.err == nil && len(node.Texts) != len(node.Exprs)+1 {
+			d.fail("texts: got %d for %d exprs, want %d", len(node.Texts), len(node.Exprs), len(node.Exprs)+1)
+		}
+		return node
+	case "IndexExpression":
+		d.require("left", "index")
+		return &IndexExpression{Token: d.token(), Left: d.expression("left"), Index: d.expression("index")}
+	case "SliceExpression":
+		d.require("left")
+		return &SliceExpression{Token: d.token(), Left: d.expression("left"), Start: d.expression("start"), End: d.expression("end")}
+	case "MemberExpression":
+		d.require("object", "property")
+		return &MemberExpression{Token: d.token(), Object: d.expression("object"), Property: d.identifier("property")}
+	case "HashLiteral":
+		var pairs []map[string]json.RawMessage
+		d.value("pairs", &pairs)
+		var end jsonToken
+		d.value("end", &end)
+		node := &HashLiteral{Token: d.token(), Pairs: []HashPair{}, End: end.token()}
+		for _, pair := range pairs {
+			pd := &decoder{obj: pair}
+			pd.require("key", "value")
+			key, value := pd.expression("key"), pd.expression("value")
+			if pd.err != nil {
+				d.fail("pairs: %s", pd.err)
+			}
+			node.Pairs = append(node.Pairs, HashPair{Key: key, Value: value})
+		}
+		return node
+	case "":
+		d.fail("node without a kind")
+	default:
+		d.fail("unknown node kind %q", kind)
+	}
+	return nil
+}
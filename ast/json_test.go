@@ -0,0 +1,111 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	programs := append(readmeExamples(t),
+		parse(t, `fun(x) { return -x; };`),
+		parse(t, `lit f = fun() -> int {}; fr (yea) { 1 };`),
+		parse(t, `"a ${b} ${"c${d}"}"`),
+	)
+
+	for _, program := range programs {
+		data, err := json.Marshal(program)
+		if err != nil {
+			t.Fatalf("%q: Marshal returned error: %s", program.String(), err)
+		}
+
+		var decoded ast.Program
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%q: Unmarshal returned error: %s\n%s", program.String(), err, data)
+		}
+
+		again, err := json.Marshal(&decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(data) {
+			t.Errorf("JSON changed in a round trip.\nbefore: %s\nafter:  %s", data, again)
+		}
+		if decoded.String() != program.String() {
+			t.Errorf("String changed in a round trip. expected %q, got %q", program.String(), decoded.String())
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	program := parse(t, `lit x: int = -1;`)
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"kind":"Program","statements":[` +
+		`{"kind":"LetStatement","token":{"type":"LET","literal":"lit","line":1,"column":1},` +
+		`"name":{"kind":"Identifier","token":{"type":"IDENT","literal":"x","line":1,"column":5},"value":"x",` +
+		`"type":{"kind":"TypeAnnotation","token":{"type":"IDENT","literal":"int","line":1,"column":8},"name":"int"}},` +
+		`"value":{"kind":"PrefixExpression","token":{"type":"-","literal":"-","line":1,"column":14},"operator":"-",` +
+		`"right":{"kind":"IntegerLiteral","token":{"type":"INT","literal":"1","line":1,"column":15},"value":1}}}]}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nexpected: %s\ngot:      %s", expected, data)
+	}
+}
+
+func TestHashLiteralJSONPairsInSourceOrder(t *testing.T) {
+	program := parse(t, `{"b": 1, "a": 2};`)
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(data), `"value":"b"`) > strings.Index(string(data), `"value":"a"`) {
+		t.Errorf("expected the pairs in source order, got %s", data)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "cannot unmarshal array"},
+		{`{"kind":"Nope"}`, `ast: unknown node kind "Nope"`},
+		{`{"statements":[]}`, "ast: node without a kind"},
+		{`{"kind":"IntegerLiteral","value":1}`, "ast: expected a Program, got IntegerLiteral"},
+		{`{"kind":"Program","statements":[{"kind":"IntegerLiteral"}]}`, "ast: statements: unexpected IntegerLiteral"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"Boolean","value":"yes"}}]}`,
+			"ast: statements[0]: expression: value:"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"TemplateLiteral","texts":["a"],"exprs":[{"kind":"Identifier","value":"b"}]}}]}`,
+			"ast: statements[0]: expression: texts: got 1 for 1 exprs, want 2"},
+		{`{"kind":"Program","statements":[{"kind":"LetStatement"}]}`, "ast: statements[0]: name: missing"},
+		{`{"kind":"Program","statements":[{"kind":"LetStatement","name":{"kind":"Identifier","value":"x"},"value":null}]}`,
+			"ast: statements[0]: value: missing"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"InfixExpression","operator":"+","left":{"kind":"IntegerLiteral","value":1}}}]}`,
+			"ast: statements[0]: expression: right: missing"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"IfExpression","condition":{"kind":"Boolean","value":true}}}]}`,
+			"ast: statements[0]: expression: consequence: missing"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"FunctionLiteral","parameters":[]}}]}`,
+			"ast: statements[0]: expression: body: missing"},
+		{`{"kind":"Program","statements":[{"kind":"ExpressionStatement","expression":{"kind":"HashLiteral","pairs":[{"key":{"kind":"IntegerLiteral","value":1}}]}}]}`,
+			"ast: statements[0]: expression: pairs: value: missing"},
+	}
+
+	for _, tt := range tests {
+		var program ast.Program
+		err := json.Unmarshal([]byte(tt.input), &program)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ast
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Resolve annotates node for slot-indexed variable access. Every function
+// literal gets the names of its call frame slots in Locals: its parameters
+// followed by the names its body binds with `lit` or `import`, outside nested
+// functions. Every identifier bound by an enclosing function gets the depth
+// and slot of that binding; the rest are globals, looked up by name at runtime
+// so the REPL can keep adding them.
+//
+// A slot is reserved for the whole function even though its `lit` may not
+// have run yet. Reading a slot that is still unset falls back to a lookup by
+// name, which sees the binding the name had before, exactly like nested maps.
+//
+// Resolve writes to the nodes of the tree, so it must not run while the tree
+// is evaluated. The parser and the JSON decoder resolve the trees they return
+// and evaluation only reads them, so one parsed program can be run by several
+// interpreters at once. Trees built by hand and never resolved still run,
+// with every variable looked up by name.
+func Resolve(node Node) {
+	r := &resolver{}
+	r.resolve(node)
+}
+
+type resolver struct {
+	scopes []map[string]int // the slot of each name, per enclosing function, innermost last
+}
+
+func (r *resolver) resolve(node Node) {
+	switch node := node.(type) {
+	case *Identifier:
+		r.identifier(node)
+	case *LetStatement:
+		for _, child := range frameChildren(node) {
+			r.resolve(child)
+		}
+		r.identifier(node.Name)
+	case *FunctionLiteral:
+		r.function(node)
+	default:
+		for _, child := range frameChildren(node) {
+			r.resolve(child)
+		}
+	}
+}
+
+func (r *resolver) function(fn *FunctionLiteral) {
+	slots := map[string]int{}
+	fn.Locals = nil
+	bind := func(name string) {
+		if _, ok := slots[name]; !ok {
+			slots[name] = len(fn.Locals)
+			fn.Locals = append(fn.Locals, name)
+		}
+	}
+
+	for _, param := range fn.Parameters {
+		bind(param.Value)
+	}
+	Declarations(fn.Body, bind)
+
+	r.scopes = append(r.scopes, slots)
+	for _, param := range fn.Parameters {
+		r.identifier(param)
+	}
+	r.resolve(fn.Body)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *resolver) identifier(id *Identifier) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, ok := r.scopes[i][id.Value]; ok {
+			id.Local, id.Depth, id.Slot = true, len(r.scopes)-1-i, slot
+			return
+		}
+	}
+	id.Local, id.Depth, id.Slot = false, 0, 0
+}
+
+// Declarations calls bind with every name node binds in the frame it runs in,
+// with `lit` or `import`, skipping nested functions.
+func Declarations(node Node, bind func(name string)) {
+	Inspect(node, func(n Node) bool {
+		switch n := n.(type) {
+		case *FunctionLiteral:
+			return false
+		case *LetStatement:
+			bind(n.Name.Value)
+		case *ImportStatement:
+			bind(importName(n))
+		}
+		return true
+	})
+}
+
+// importName is the name an import statement binds the module to, the alias or
+// the base name of the path.
+func importName(node *ImportStatement) string {
+	if node.Alias != nil {
+		return node.Alias.Value
+	}
+	return strings.TrimSuffix(filepath.Base(node.Path.Value), ".zzz")
+}
+
+// frameChildren returns the nodes directly below node that are evaluated in the
+// same frame as node, in evaluation order. These are its Children, except the
+// body of a function literal, which runs in a frame of its own, and the names
+// that are not variable references: what a `lit` or `import` binds and the
+// property of a member expression.
+func frameChildren(node Node) []Node {
+	var name Node
+	switch node := node.(type) {
+	case *FunctionLiteral:
+		return nil
+	case *LetStatement:
+		name = node.Name
+	case *ImportStatement:
+		name = node.Alias
+	case *MemberExpression:
+		name = node.Property
+	}
+
+	var out []Node
+	for _, child := range Children(node) {
+		if child != name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
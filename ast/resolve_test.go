@@ -0,0 +1,57 @@
+package ast_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+)
+
+func TestResolve(t *testing.T) {
+	// The parser resolves the programs it returns.
+	program := parse(t, `
+lit top = 1;
+lit outer = fun(a, b) {
+	lit c = a;
+	fr (b) { lit d = 2; } lowkey { import "std/math"; }
+	fun(e) { a + c + e + top + d + math }
+};`)
+
+	outer := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if want := []string{"a", "b", "c", "d", "math"}; !reflect.DeepEqual(outer.Locals, want) {
+		t.Errorf("wrong outer locals. want=%v, got=%v", want, outer.Locals)
+	}
+
+	inner := outer.Body.Statements[2].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if want := []string{"e"}; !reflect.DeepEqual(inner.Locals, want) {
+		t.Errorf("wrong inner locals. want=%v, got=%v", want, inner.Locals)
+	}
+
+	type resolution struct {
+		local       bool
+		depth, slot int
+	}
+	expected := map[string]resolution{
+		"a":    {true, 1, 0},
+		"c":    {true, 1, 2},
+		"e":    {true, 0, 0},
+		"top":  {false, 0, 0},
+		"d":    {true, 1, 3},
+		"math": {true, 1, 4},
+	}
+
+	var identifiers func(expr ast.Expression)
+	identifiers = func(expr ast.Expression) {
+		switch expr := expr.(type) {
+		case *ast.InfixExpression:
+			identifiers(expr.Left)
+			identifiers(expr.Right)
+		case *ast.Identifier:
+			want := expected[expr.Value]
+			if got := (resolution{expr.Local, expr.Depth, expr.Slot}); got != want {
+				t.Errorf("wrong resolution of %s. want=%+v, got=%+v", expr.Value, want, got)
+			}
+		}
+	}
+	identifiers(inner.Body.Statements[0].(*ast.ExpressionStatement).Expression)
+}
@@ -0,0 +1,234 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called by Walk for every node. If it returns a
+// non-nil visitor w, Walk visits each child of the node with w, followed by a
+// call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node depth-first, in source order. Hash
+// literal pairs are visited key then value.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node like Walk, calling f for every
+// node. The children of a node are skipped if f returns false for it. After
+// the children of a node, f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Children returns the nodes directly below node in source order, the same
+// nodes Walk visits. Fields that are nil are left out.
+func Children(node Node) []Node {
+	var out []Node
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if !isNil(n) {
+				out = append(out, n)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *LetStatement:
+		add(node.Name, node.Value)
+	case *ReturnStatement:
+		add(node.ReturnValue)
+	case *ImportStatement:
+		add(node.Path, node.Alias)
+	case *ExpressionStatement:
+		add(node.Expression)
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *Identifier:
+		add(node.Type)
+	case *PrefixExpression:
+		add(node.Right)
+	case *InfixExpression:
+		add(node.Left, node.Right)
+	case *IfExpression:
+		add(node.Condition, node.Consequence, node.Alternative)
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			add(param)
+		}
+		add(node.ReturnType, node.Body)
+	case *CallExpression:
+		add(node.Function)
+		for _, arg := range node.Arguments {
+			add(arg)
+		}
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			add(el)
+		}
+	case *TemplateLiteral:
+		for _, expr := range node.Exprs {
+			add(expr)
+		}
+	case *IndexExpression:
+		add(node.Left, node.Index)
+	case *SliceExpression:
+		add(node.Left, node.Start, node.End)
+	case *MemberExpression:
+		add(node.Object, node.Property)
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			add(pair.Key, pair.Value)
+		}
+	case *IntegerLiteral, *StringLiteral, *Boolean, *TypeAnnotation:
+		// leaves
+	default:
+		panic(fmt.Sprintf("ast: unexpected node type %T", node))
+	}
+	return out
+}
+
+// Rewrite traverses the tree rooted at node depth-first and replaces every
+// node n with f(n), children before their parents, so f sees a node whose
+// children have already been rewritten. f returns n itself to keep it. It
+// returns the replacement of node.
+//
+// A replacement must fit the field it goes into: a statement for a statement,
+// an expression for an expression, and a node of the same type for fields like
+// a let statement's name or a function's body. Rewrite panics otherwise.
+//
+// Rewrite does not update what Resolve recorded: a function's Locals and the
+// slots of its identifiers are stale once bindings change, and new identifiers
+// have no slot. Callers must run Resolve again on the whole program, not only
+// on the rewritten nodes, since slots depend on the enclosing functions.
+func Rewrite(node Node, f func(Node) Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		rewriteStatements(node.Statements, f)
+	case *LetStatement:
+		node.Name = rewriteAs[*Identifier](node.Name, f)
+		node.Value = rewriteAs[Expression](node.Value, f)
+	case *ReturnStatement:
+		node.ReturnValue = rewriteAs[Expression](node.ReturnValue, f)
+	case *ImportStatement:
+		node.Path = rewriteAs[*StringLiteral](node.Path, f)
+		node.Alias = rewriteAs[*Identifier](node.Alias, f)
+	case *ExpressionStatement:
+		node.Expression = rewriteAs[Expression](node.Expression, f)
+	case *BlockStatement:
+		rewriteStatements(node.Statements, f)
+	case *Identifier:
+		node.Type = rewriteAs[*TypeAnnotation](node.Type, f)
+	case *PrefixExpression:
+		node.Right = rewriteAs[Expression](node.Right, f)
+	case *InfixExpression:
+		node.Left = rewriteAs[Expression](node.Left, f)
+		node.Right = rewriteAs[Expression](node.Right, f)
+	case *IfExpression:
+		node.Condition = rewriteAs[Expression](node.Condition, f)
+		node.Consequence = rewriteAs[*BlockStatement](node.Consequence, f)
+		node.Alternative = rewriteAs[*BlockStatement](node.Alternative, f)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = rewriteAs[*Identifier](param, f)
+		}
+		node.ReturnType = rewriteAs[*TypeAnnotation](node.ReturnType, f)
+		node.Body = rewriteAs[*BlockStatement](node.Body, f)
+	case *CallExpression:
+		node.Function = rewriteAs[Expression](node.Function, f)
+		rewriteExpressions(node.Arguments, f)
+	case *ArrayLiteral:
+		rewriteExpressions(node.Elements, f)
+	case *TemplateLiteral:
+		rewriteExpressions(node.Exprs, f)
+	case *IndexExpression:
+		node.Left = rewriteAs[Expression](node.Left, f)
+		node.Index = rewriteAs[Expression](node.Index, f)
+	case *SliceExpression:
+		node.Left = rewriteAs[Expression](node.Left, f)
+		node.Start = rewriteAs[Expression](node.Start, f)
+		node.End = rewriteAs[Expression](node.End, f)
+	case *MemberExpression:
+		node.Object = rewriteAs[Expression](node.Object, f)
+		node.Property = rewriteAs[*Identifier](node.Property, f)
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key = rewriteAs[Expression](pair.Key, f)
+			node.Pairs[i].Value = rewriteAs[Expression](pair.Value, f)
+		}
+	}
+
+	return f(node)
+}
+
+func rewriteStatements(stmts []Statement, f func(Node) Node) {
+	for i, stmt := range stmts {
+		stmts[i] = rewriteAs[Statement](stmt, f)
+	}
+}
+
+func rewriteExpressions(exprs []Expression, f func(Node) Node) {
+	for i, expr := range exprs {
+		exprs[i] = rewriteAs[Expression](expr, f)
+	}
+}
+
+// rewriteAs rewrites node and returns the replacement as a T. Nil fields stay
+// nil.
+func rewriteAs[T Node](node T, f func(Node) Node) T {
+	if isNil(node) {
+		return node
+	}
+	replaced := Rewrite(node, f)
+	t, ok := replaced.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast: cannot replace %T with %T", node, replaced))
+	}
+	return t
+}
+
+// isNil reports whether node is nil or a nil pointer, as optional fields like
+// IfExpression.Alternative are when they are converted to a Node.
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	switch node := node.(type) {
+	case *Identifier:
+		return node == nil
+	case *TypeAnnotation:
+		return node == nil
+	case *BlockStatement:
+		return node == nil
+	case *StringLiteral:
+		return node == nil
+	}
+	return false
+}
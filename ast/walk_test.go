@@ -0,0 +1,285 @@
+package ast_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/parser"
+	"github.com/amirhesham65/zzz-lang/token"
+)
+
+// readmeExamples returns the parsed zzz code blocks of the README.
+func readmeExamples(t *testing.T) []*ast.Program {
+	t.Helper()
+	readme, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := regexp.MustCompile("(?s)```zzz\n(.*?)```").FindAllSubmatch(readme, -1)
+	if len(blocks) == 0 {
+		t.Fatal("no zzz examples found in the README")
+	}
+
+	var programs []*ast.Program
+	for _, block := range blocks {
+		programs = append(programs, parse(t, string(block[1])))
+	}
+	return programs
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+// countingVisitor counts the nodes it visits by type and checks that every
+// Visit(nil) closes a node it entered.
+type countingVisitor struct {
+	counts map[string]int
+	depth  *int
+}
+
+func (v countingVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	*v.depth++
+	v.counts[fmt.Sprintf("%T", node)]++
+	return v
+}
+
+func TestWalkReadmeExamples(t *testing.T) {
+	counts := map[string]int{}
+	// The README has no return statement or prefix operator.
+	programs := append(readmeExamples(t), parse(t, `fun(x) { return -x; };`))
+	for _, program := range programs {
+		depth := 0
+		ast.Walk(countingVisitor{counts: counts, depth: &depth}, program)
+		if depth != 0 {
+			t.Errorf("unbalanced Visit(nil) calls for %q: depth %d", program.String(), depth)
+		}
+	}
+
+	nodes := []ast.Node{
+		&ast.Program{}, &ast.LetStatement{}, &ast.ReturnStatement{}, &ast.ImportStatement{},
+		&ast.ExpressionStatement{}, &ast.BlockStatement{}, &ast.Identifier{}, &ast.TypeAnnotation{},
+		&ast.IntegerLiteral{}, &ast.StringLiteral{}, &ast.Boolean{}, &ast.PrefixExpression{},
+		&ast.InfixExpression{}, &ast.IfExpression{}, &ast.FunctionLiteral{}, &ast.CallExpression{},
+		&ast.ArrayLiteral{}, &ast.IndexExpression{}, &ast.MemberExpression{}, &ast.HashLiteral{},
+	}
+	for _, node := range nodes {
+		name := fmt.Sprintf("%T", node)
+		if counts[name] == 0 {
+			t.Errorf("Walk never visited a %s", name)
+		}
+	}
+}
+
+func TestInspectMatchesChildren(t *testing.T) {
+	for _, program := range readmeExamples(t) {
+		// Inspect visits a node, then its children and their subtrees in the
+		// order Children returns them.
+		var visited []ast.Node
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n != nil {
+				visited = append(visited, n)
+			}
+			return true
+		})
+
+		var expected []ast.Node
+		var preorder func(ast.Node)
+		preorder = func(n ast.Node) {
+			expected = append(expected, n)
+			for _, child := range ast.Children(n) {
+				preorder(child)
+			}
+		}
+		preorder(program)
+
+		if !reflect.DeepEqual(visited, expected) {
+			t.Errorf("Inspect visited %d nodes of %q, want %d", len(visited), program.String(), len(expected))
+		}
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, `lit f = fun(x) { x + 1 }; f(2);`)
+
+	var integers []int64
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.IntegerLiteral:
+			integers = append(integers, n.Value)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(integers, []int64{2}) {
+		t.Errorf("expected only the integer outside the function, got %v", integers)
+	}
+}
+
+func TestHashLiteralPairsInSourceOrder(t *testing.T) {
+	program := parse(t, `{"c": 1, "a": 2, "b": [3]}`)
+
+	var visited []string
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.StringLiteral:
+			visited = append(visited, n.Value)
+		case *ast.IntegerLiteral:
+			visited = append(visited, fmt.Sprint(n.Value))
+		}
+		return true
+	})
+
+	expected := []string{"c", "1", "a", "2", "b", "3"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+}
+
+func TestRewriteIdentity(t *testing.T) {
+	nodes := func(program *ast.Program) []ast.Node {
+		var out []ast.Node
+		ast.Inspect(program, func(n ast.Node) bool {
+			out = append(out, n)
+			return true
+		})
+		return out
+	}
+
+	for _, program := range readmeExamples(t) {
+		before := nodes(program)
+		rewritten := ast.Rewrite(program, func(n ast.Node) ast.Node { return n })
+		if rewritten != program {
+			t.Errorf("Rewrite returned a different root for %q", program.String())
+		}
+		if !reflect.DeepEqual(nodes(program), before) {
+			t.Errorf("identity rewrite changed the nodes of %q", program.String())
+		}
+	}
+}
+
+func TestRewriteReplacesNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lit x = 1 + 2;`, `lit x = (10 + 20);`},
+		{`fun(a) { a * 3 }(4);`, `fun(a) (a * 30)(40)`},
+		{`fr (x > 5) { [6] } lowkey { x[7] };`, `fr(x > 50) [60]lowkey (x[70])`},
+		{`{8: 9};`, `{80: 90}`},
+		{`return -1;`, `return (-10);`},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		ast.Rewrite(program, func(n ast.Node) ast.Node {
+			if il, ok := n.(*ast.IntegerLiteral); ok {
+				value := il.Value * 10
+				tok := token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10)}
+				return &ast.IntegerLiteral{Token: tok, Value: value}
+			}
+			return n
+		})
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestRewriteChildrenFirst(t *testing.T) {
+	program := parse(t, `1 + 2 * 3;`)
+
+	var order []string
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		switch n.(type) {
+		case *ast.IntegerLiteral, *ast.InfixExpression:
+			order = append(order, n.String())
+		}
+		return n
+	})
+
+	expected := []string{"1", "2", "3", "(2 * 3)", "(1 + (2 * 3))"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+}
+
+func TestRewriteHashKeys(t *testing.T) {
+	program := parse(t, `{"a": 1};`)
+
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if sl, ok := n.(*ast.StringLiteral); ok {
+			return &ast.StringLiteral{Token: sl.Token, Value: sl.Value + "!"}
+		}
+		return n
+	})
+
+	hash := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.HashLiteral)
+	if len(hash.Pairs) != 1 {
+		t.Fatalf("expected 1 pair, got %d", len(hash.Pairs))
+	}
+	if key := hash.Pairs[0].Key.(*ast.StringLiteral); key.Value != "a!" {
+		t.Errorf("expected the key to be rewritten, got %q", key.Value)
+	}
+}
+
+func TestResolveAfterRewrite(t *testing.T) {
+	program := parse(t, `fun(a) { lit b = 1; a + b };`)
+
+	// Rename b to c, which leaves the slots Resolve recorded out of date.
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if id, ok := n.(*ast.Identifier); ok && id.Value == "b" {
+			return &ast.Identifier{Token: id.Token, Value: "c"}
+		}
+		return n
+	})
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if want := []string{"a", "b"}; !reflect.DeepEqual(fn.Locals, want) {
+		t.Fatalf("expected stale locals %v, got %v", want, fn.Locals)
+	}
+
+	ast.Resolve(program)
+	if want := []string{"a", "c"}; !reflect.DeepEqual(fn.Locals, want) {
+		t.Errorf("expected locals %v, got %v", want, fn.Locals)
+	}
+	sum := fn.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	if c := sum.Right.(*ast.Identifier); !c.Local || c.Slot != 1 {
+		t.Errorf("c is not resolved to slot 1. got local=%t slot=%d", c.Local, c.Slot)
+	}
+}
+
+func TestRewriteTypeMismatchPanics(t *testing.T) {
+	program := parse(t, `lit x = 1;`)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when replacing a let statement's name with a literal")
+		}
+	}()
+	ast.Rewrite(program, func(n ast.Node) ast.Node {
+		if id, ok := n.(*ast.Identifier); ok {
+			return &ast.StringLiteral{Token: id.Token, Value: id.Value}
+		}
+		return n
+	})
+}
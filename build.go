@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/zzz"
+)
+
+// build compiles a program to a .zzzc file that `zzz` runs without parsing it again.
+func build(args []string) int {
+	flags := flag.NewFlagSet("zzz build", flag.ExitOnError)
+	output := flags.String("o", "", "output file (default: the input with a .zzzc extension)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: zzz build [-o out.zzzc] file.zzz")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+
+	bytecode, err := zzz.CompileFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", path, err)
+		return 1
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(path, filepath.Ext(path)) + ".zzzc"
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/checker"
+	"github.com/amirhesham65/zzz-lang/zzz"
+)
+
+// check reports problems found in programs without running them. It fails if
+// any of them is an error.
+func check(args []string) int {
+	flags := flag.NewFlagSet("zzz check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: zzz check file.zzz...")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		diagnostics, err := zzz.CheckFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", path, d)
+			if d.Severity == checker.Error {
+				status = 1
+			}
+		}
+	}
+	return status
+}
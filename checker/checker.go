@@ -0,0 +1,526 @@
+// Package checker finds mistakes in programs without running them.
+package checker
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/token"
+)
+
+// Severity tells whether a Diagnostic is certain to fail at runtime.
+type Severity int
+
+const (
+	Warning Severity = iota // suspicious, but the program may still run correctly
+	Error                   // fails at runtime when the code is reached
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic is a problem found at a position of the source.
+type Diagnostic struct {
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Check reports, sorted by position,
+//
+//   - identifiers that are neither bound in an enclosing scope nor builtins,
+//   - `lit` bindings and parameters of functions that are never read,
+//   - bindings that shadow a builtin,
+//   - statements after a `return`,
+//   - calls of a function literal, directly or through a name bound only to
+//     it, with the wrong number of arguments,
+//   - type errors, see typ.
+//
+// Scopes follow the evaluator: only functions open one, and a name can be read
+// anywhere in the function that binds it, including functions nested in it
+// that run after the binding. Top-level bindings are never reported as unused,
+// since other files can import them. Names starting with an underscore are
+// never reported as unused either.
+func Check(program *ast.Program) []Diagnostic {
+	c := &checker{builtins: evaluator.NewBuiltins()}
+
+	global := c.open(nil, program.Statements)
+	global.global = true
+	c.statements(program.Statements)
+	c.close()
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i], c.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diagnostics
+}
+
+type checker struct {
+	builtins    map[string]*object.Builtin
+	scopes      []*scope // innermost last
+	diagnostics []Diagnostic
+}
+
+type scope struct {
+	global   bool
+	result   typ // declared result type of the function
+	bindings map[string]*binding
+	order    []*binding
+}
+
+type binding struct {
+	name      string
+	kind      string // "parameter" or "lit"; imports are "import"
+	at        token.Token
+	reads     int
+	decls     int  // number of `lit`s and parameters binding the name in its scope
+	typ       typ  // the annotated type, or the type inferred from its only value
+	annotated bool // whether typ is annotated
+}
+
+func (c *checker) report(at token.Token, severity Severity, format string, a ...any) {
+	c.diagnostics = append(c.diagnostics, Diagnostic{
+		Line:     at.Line,
+		Column:   at.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// open pushes the scope of a function with params whose body is stmts, with
+// every name the body binds already declared.
+func (c *checker) open(params []*ast.Identifier, stmts []ast.Statement) *scope {
+	s := &scope{bindings: map[string]*binding{}}
+	c.scopes = append(c.scopes, s)
+
+	for _, param := range params {
+		c.declare(param, "parameter", nil)
+	}
+	for _, stmt := range stmts {
+		c.declarations(stmt)
+	}
+	return s
+}
+
+// close pops the innermost scope and reports its unused bindings.
+func (c *checker) close() {
+	s := c.scopes[len(c.scopes)-1]
+	c.scopes = c.scopes[:len(c.scopes)-1]
+
+	if s.global {
+		return
+	}
+	for _, b := range s.order {
+		if b.reads == 0 && b.kind != "import" && !strings.HasPrefix(b.name, "_") {
+			c.report(b.at, Warning, "%s %s is never used", b.kind, b.name)
+		}
+	}
+}
+
+func (c *checker) declare(name *ast.Identifier, kind string, value ast.Expression) {
+	s := c.scopes[len(c.scopes)-1]
+
+	if _, ok := c.builtins[name.Value]; ok {
+		c.report(name.Token, Warning, "%s %s shadows the builtin %s", kind, name.Value, name.Value)
+	}
+
+	t, annotated := dynamic, name.Type != nil
+	if annotated {
+		t = annotationType(name.Type)
+	} else if fn, ok := value.(*ast.FunctionLiteral); ok {
+		t = functionType(fn)
+	} else if kind == "import" {
+		t = moduleType
+	}
+
+	b, ok := s.bindings[name.Value]
+	if !ok {
+		b = &binding{name: name.Value, kind: kind, at: name.Token, typ: t, annotated: annotated}
+		s.bindings[name.Value] = b
+		s.order = append(s.order, b)
+	}
+	b.decls++
+
+	switch {
+	case b.decls == 1:
+	case annotated && !b.annotated:
+		b.typ, b.annotated = t, true
+	case !b.annotated:
+		// Bound more than once, so the name may hold either value.
+		b.typ = dynamic
+	}
+}
+
+// declarations declares the names node binds in the current scope, without
+// looking into nested functions.
+func (c *checker) declarations(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		c.declare(node.Name, "lit", node.Value)
+		c.declarations(node.Value)
+	case *ast.ImportStatement:
+		name := node.Alias
+		if name == nil {
+			base := strings.TrimSuffix(filepath.Base(node.Path.Value), ".zzz")
+			name = &ast.Identifier{Token: node.Token, Value: base}
+		}
+		c.declare(name, "import", nil)
+	case *ast.ReturnStatement:
+		c.declarations(node.ReturnValue)
+	case *ast.ExpressionStatement:
+		c.declarations(node.Expression)
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			c.declarations(stmt)
+		}
+	case *ast.IfExpression:
+		c.declarations(node.Condition)
+		c.declarations(node.Consequence)
+		if node.Alternative != nil {
+			c.declarations(node.Alternative)
+		}
+	case *ast.PrefixExpression:
+		c.declarations(node.Right)
+	case *ast.InfixExpression:
+		c.declarations(node.Left)
+		c.declarations(node.Right)
+	case *ast.CallExpression:
+		c.declarations(node.Function)
+		for _, arg := range node.Arguments {
+			c.declarations(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			c.declarations(el)
+		}
+	case *ast.TemplateLiteral:
+		for _, expr := range node.Exprs {
+			c.declarations(expr)
+		}
+	case *ast.IndexExpression:
+		c.declarations(node.Left)
+		c.declarations(node.Index)
+	case *ast.SliceExpression:
+		c.declarations(node.Left)
+		c.declarations(node.Start)
+		c.declarations(node.End)
+	case *ast.MemberExpression:
+		c.declarations(node.Object)
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			c.declarations(pair.Key)
+			c.declarations(pair.Value)
+		}
+	}
+}
+
+func (c *checker) lookup(name string) *binding {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if b, ok := c.scopes[i].bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// statements checks a statement list and returns the type of its value, the
+// value of its last statement.
+func (c *checker) statements(stmts []ast.Statement) typ {
+	result := dynamic
+	for i, stmt := range stmts {
+		if i > 0 {
+			if _, ok := stmts[i-1].(*ast.ReturnStatement); ok {
+				c.report(statementToken(stmt), Warning, "unreachable code after return")
+			}
+		}
+		result = c.node(stmt)
+	}
+	return result
+}
+
+// node checks node and returns the type of its value.
+func (c *checker) node(node ast.Node) typ {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		c.let(node)
+	case *ast.ReturnStatement:
+		value := c.node(node.ReturnValue)
+		c.returns(node.Token, value)
+	case *ast.ExpressionStatement:
+		return c.node(node.Expression)
+	case *ast.BlockStatement:
+		return c.statements(node.Statements)
+	case *ast.Identifier:
+		if b := c.lookup(node.Value); b != nil {
+			b.reads++
+			return b.typ
+		}
+		if _, ok := c.builtins[node.Value]; ok {
+			return builtinType
+		}
+		c.report(node.Token, Error, "undefined identifier: %s", node.Value)
+	case *ast.FunctionLiteral:
+		return c.function(node)
+	case *ast.CallExpression:
+		return c.call(node)
+	case *ast.IfExpression:
+		c.node(node.Condition)
+		consequence := c.node(node.Consequence)
+		if node.Alternative == nil {
+			return dynamic
+		}
+		if alternative := c.node(node.Alternative); alternative.same(consequence) {
+			return consequence
+		}
+	case *ast.PrefixExpression:
+		right := c.node(node.Right)
+		result, err := prefixType(node.Operator, right)
+		if err != "" {
+			c.report(node.Token, Error, "%s", err)
+		}
+		return result
+	case *ast.InfixExpression:
+		left := c.node(node.Left)
+		right := c.node(node.Right)
+		result, err := infixType(node.Operator, left, right)
+		if err != "" {
+			c.report(node.Token, Error, "%s", err)
+		}
+		return result
+	case *ast.IntegerLiteral:
+		return intType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.Boolean:
+		return boolType
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			c.node(el)
+		}
+		return arrayType
+	case *ast.TemplateLiteral:
+		for _, expr := range node.Exprs {
+			c.node(expr)
+		}
+		return stringType
+	case *ast.IndexExpression:
+		left := c.node(node.Left)
+		index := c.node(node.Index)
+		if err := indexError(left, index); err != "" {
+			c.report(node.Token, Error, "%s", err)
+		}
+		if left.same(stringType) {
+			return stringType
+		}
+	case *ast.SliceExpression:
+		left := c.node(node.Left)
+		var bounds []typ
+		for _, bound := range []ast.Expression{node.Start, node.End} {
+			if bound != nil {
+				bounds = append(bounds, c.node(bound))
+			}
+		}
+		if err := sliceError(left, bounds...); err != "" {
+			c.report(node.Token, Error, "%s", err)
+		}
+		if left.same(stringType) || left.same(arrayType) {
+			return left
+		}
+	case *ast.MemberExpression:
+		c.node(node.Object)
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			c.node(pair.Key)
+			c.node(pair.Value)
+		}
+		return hashType
+	}
+	return dynamic
+}
+
+func (c *checker) let(node *ast.LetStatement) {
+	if node.Name.Type != nil {
+		c.annotation(node.Name.Type)
+	}
+	value := c.node(node.Value)
+
+	b := c.lookup(node.Name.Value)
+	switch {
+	case b.annotated:
+		if !value.assignableTo(b.typ) {
+			c.report(node.Name.Token, Error, "cannot use %s as %s in lit %s", value, b.typ, node.Name.Value)
+		}
+	case b.decls == 1:
+		b.typ = value
+	}
+}
+
+// returns checks a value returned by the function being checked.
+func (c *checker) returns(at token.Token, value typ) {
+	s := c.scopes[len(c.scopes)-1]
+	if !s.global && !value.assignableTo(s.result) {
+		c.report(at, Error, "cannot return %s from a function returning %s", value, s.result)
+	}
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral) typ {
+	for _, param := range fn.Parameters {
+		if param.Type != nil {
+			c.annotation(param.Type)
+		}
+	}
+	if fn.ReturnType != nil {
+		c.annotation(fn.ReturnType)
+	}
+
+	t := functionType(fn)
+	s := c.open(fn.Parameters, fn.Body.Statements)
+	s.result = t.sig.result
+
+	body := c.statements(fn.Body.Statements)
+	if n := len(fn.Body.Statements); n > 0 {
+		if last, ok := fn.Body.Statements[n-1].(*ast.ExpressionStatement); ok {
+			c.returns(last.Token, body)
+		}
+	}
+
+	c.close()
+	return t
+}
+
+// call checks a call and returns the type of its result. Calls of functions
+// with a known signature have their arguments checked against it.
+func (c *checker) call(call *ast.CallExpression) typ {
+	callee := c.node(call.Function)
+	args := make([]typ, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		args[i] = c.node(arg)
+	}
+
+	name, at := "function", call.Token
+	if id, ok := call.Function.(*ast.Identifier); ok {
+		name, at = id.Value, id.Token
+		if c.lookup(id.Value) == nil {
+			if result, ok := c.builtinCall(id, call.Arguments, args); ok {
+				return result
+			}
+		}
+	}
+
+	if callee.name != "" && callee.name != "fun" {
+		c.report(at, Error, "not a function: %s", callee.runtimeName())
+		return dynamic
+	}
+	if callee.sig == nil {
+		return dynamic
+	}
+
+	params := callee.sig.params
+	if len(params) != len(args) {
+		c.report(at, Error, "wrong number of arguments to %s. got=%d, want=%d", name, len(args), len(params))
+		return callee.sig.result
+	}
+	for i, arg := range args {
+		if !arg.assignableTo(params[i]) {
+			c.report(expressionToken(call.Arguments[i]), Error, "cannot use %s as %s in argument %d to %s", arg, params[i], i+1, name)
+		}
+	}
+	return callee.sig.result
+}
+
+// builtinCall checks a call of one of the builtins whose signature is known.
+func (c *checker) builtinCall(id *ast.Identifier, exprs []ast.Expression, args []typ) (typ, bool) {
+	builtin, ok := builtinSignatures[id.Value]
+	if !ok {
+		return dynamic, false
+	}
+
+	if builtin.arity >= 0 && len(args) != builtin.arity {
+		c.report(id.Token, Error, "wrong number of arguments to %s. got=%d, want=%d", id.Value, len(args), builtin.arity)
+		return builtin.result, true
+	}
+	if builtin.check != nil {
+		for i, arg := range args {
+			if err := builtin.check(i, arg); err != "" {
+				c.report(expressionToken(exprs[i]), Error, "%s", err)
+			}
+		}
+	}
+	return builtin.result, true
+}
+
+// annotation reports an annotation naming an unknown type.
+func (c *checker) annotation(ta *ast.TypeAnnotation) {
+	if _, ok := typeNames[ta.Name]; !ok {
+		c.report(ta.Token, Error, "unknown type %s", ta.Name)
+	}
+}
+
+func statementToken(stmt ast.Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token
+	case *ast.ReturnStatement:
+		return stmt.Token
+	case *ast.ImportStatement:
+		return stmt.Token
+	case *ast.ExpressionStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
+// expressionToken returns the token an expression starts with.
+func expressionToken(expr ast.Expression) token.Token {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return expressionToken(expr.Left)
+	case *ast.CallExpression:
+		return expressionToken(expr.Function)
+	case *ast.IndexExpression:
+		return expressionToken(expr.Left)
+	case *ast.SliceExpression:
+		return expressionToken(expr.Left)
+	case *ast.MemberExpression:
+		return expressionToken(expr.Object)
+	case *ast.Identifier:
+		return expr.Token
+	case *ast.IntegerLiteral:
+		return expr.Token
+	case *ast.StringLiteral:
+		return expr.Token
+	case *ast.TemplateLiteral:
+		return expr.Token
+	case *ast.Boolean:
+		return expr.Token
+	case *ast.PrefixExpression:
+		return expr.Token
+	case *ast.IfExpression:
+		return expr.Token
+	case *ast.FunctionLiteral:
+		return expr.Token
+	case *ast.ArrayLiteral:
+		return expr.Token
+	case *ast.HashLiteral:
+		return expr.Token
+	}
+	return token.Token{}
+}
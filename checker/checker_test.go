@@ -0,0 +1,91 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			`lit x = 1; spitt(x);`,
+			[]string{"1:12: error: undefined identifier: spitt"},
+		},
+		{
+			"lit f = fun(a, b) {\n  lit unused = a;\n  a\n};\nf(1, 2);",
+			[]string{
+				"1:16: warning: parameter b is never used",
+				"2:7: warning: lit unused is never used",
+			},
+		},
+		{
+			"lit f = fun(_ignored) { 1 }; f(0);",
+			nil,
+		},
+		{
+			"lit len = fun(x) { x }; fun(spit) { spit };",
+			[]string{
+				"1:5: warning: lit len shadows the builtin len",
+				"1:29: warning: parameter spit shadows the builtin spit",
+			},
+		},
+		{
+			"lit f = fun(x) {\n  return x;\n  spit(x);\n};\nf(1);",
+			[]string{"3:3: warning: unreachable code after return"},
+		},
+		{
+			"lit add = fun(a, b) { a + b };\nadd(1);\nfun(x) { x }(1, 2);",
+			[]string{
+				"2:1: error: wrong number of arguments to add. got=1, want=2",
+				"3:13: error: wrong number of arguments to function. got=2, want=1",
+			},
+		},
+		{
+			// Rebinding makes the arity unknown.
+			"lit f = fun(a) { a }; lit f = fun(a, b) { a + b }; f(1, 2);",
+			nil,
+		},
+		{
+			// Functions may use bindings that come later in the enclosing function.
+			"lit main = fun() {\n  lit g = fun() { h() };\n  lit h = fun() { 1 };\n  g()\n};\nmain();",
+			nil,
+		},
+		{
+			`import "std/strings"; import "lib.zzz" as l; strings.join(l.items, ",");`,
+			nil,
+		},
+		{
+			"fr (yea) { lit y = 1; }; y + z",
+			[]string{"1:30: error: undefined identifier: z"},
+		},
+		{
+			// Top-level bindings may be used by importers.
+			"lit exported = 1;",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		diagnostics := Check(program)
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. expected=%q, got=%v", tt.input, tt.expected, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic %d for %q. expected=%q, got=%q", i, tt.input, tt.expected[i], d)
+			}
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+)
+
+// typ is the static type of a value. Types come from annotations, literals,
+// operators and the builtins in builtinSignatures. Everything else, including
+// unannotated parameters, is dynamic: compatible with every type, so code
+// without annotations is never reported.
+type typ struct {
+	name string     // a key of typeNames, or "" for dynamic
+	sig  *signature // parameters and result of a function, nil if unknown
+}
+
+type signature struct {
+	params []typ
+	result typ
+}
+
+// typeNames maps the names usable in annotations to the type of the runtime
+// objects they describe. "any" is the dynamic type.
+var typeNames = map[string]string{
+	"int":    "INTEGER",
+	"float":  "FLOAT",
+	"string": "STRING",
+	"bool":   "BOOLEAN",
+	"null":   "NULL",
+	"array":  "ARRAY",
+	"hash":   "HASH",
+	"fun":    "FUNCTION",
+	"module": "MODULE",
+	"any":    "",
+}
+
+var (
+	dynamic     = typ{}
+	intType     = typ{name: "int"}
+	floatType   = typ{name: "float"}
+	stringType  = typ{name: "string"}
+	boolType    = typ{name: "bool"}
+	nullType    = typ{name: "null"}
+	arrayType   = typ{name: "array"}
+	hashType    = typ{name: "hash"}
+	moduleType  = typ{name: "module"}
+	builtinType = typ{name: "fun"}
+)
+
+func (t typ) String() string {
+	if t.name == "" {
+		return "any"
+	}
+	return t.name
+}
+
+// runtimeName is the name the evaluator uses for values of t in errors.
+func (t typ) runtimeName() string {
+	return typeNames[t.name]
+}
+
+func (t typ) isNumber() bool {
+	return t.name == "int" || t.name == "float"
+}
+
+// same reports whether t and other are the same static type.
+func (t typ) same(other typ) bool {
+	return t.name != "" && t.name == other.name
+}
+
+// assignableTo reports whether a value of type t may be used where want is
+// declared. Integers are accepted as floats, since the operators mix them.
+func (t typ) assignableTo(want typ) bool {
+	if t.name == "" || want.name == "" || t.name == want.name {
+		return true
+	}
+	return t.name == "int" && want.name == "float"
+}
+
+// annotationType returns the type an annotation names. Unknown names, which
+// the checker reports separately, are dynamic.
+func annotationType(ta *ast.TypeAnnotation) typ {
+	if ta == nil {
+		return dynamic
+	}
+	if _, ok := typeNames[ta.Name]; !ok || ta.Name == "any" {
+		return dynamic
+	}
+	return typ{name: ta.Name}
+}
+
+// functionType returns the type of a function literal, with the signature its
+// annotations declare.
+func functionType(fn *ast.FunctionLiteral) typ {
+	sig := &signature{result: annotationType(fn.ReturnType)}
+	for _, param := range fn.Parameters {
+		sig.params = append(sig.params, annotationType(param.Type))
+	}
+	return typ{name: "fun", sig: sig}
+}
+
+// prefixType returns the type of applying a prefix operator to a value of
+// type right, or the error the evaluator would report.
+func prefixType(operator string, right typ) (typ, string) {
+	switch {
+	case operator == "!":
+		return boolType, ""
+	case right.name == "" || right.isNumber():
+		return right, ""
+	}
+	return dynamic, fmt.Sprintf("unknown operator: %s%s", operator, right.runtimeName())
+}
+
+// infixType returns the type of applying an infix operator to values of
+// types left and right, or the error the evaluator would report. It follows
+// evalInfixExpression case by case.
+func infixType(operator string, left, right typ) (typ, string) {
+	comparison := operator == "<" || operator == ">" || operator == "==" || operator == "!="
+
+	switch {
+	case left.name == "" || right.name == "":
+		if operator == "==" || operator == "!=" {
+			return boolType, ""
+		}
+		return dynamic, ""
+	case left.isNumber() && right.isNumber():
+		if comparison {
+			return boolType, ""
+		}
+		if left.name == "int" && right.name == "int" {
+			return intType, ""
+		}
+		return floatType, ""
+	case left.name == "string" && right.name == "string":
+		switch operator {
+		case "+":
+			return stringType, ""
+		case "==", "!=":
+			return boolType, ""
+		}
+	case operator == "==" || operator == "!=":
+		return boolType, ""
+	case left.name != right.name:
+		return dynamic, fmt.Sprintf("type mismatch: %s %s %s", left.runtimeName(), operator, right.runtimeName())
+	}
+	return dynamic, fmt.Sprintf("unknown operator: %s %s %s", left.runtimeName(), operator, right.runtimeName())
+}
+
+// indexError returns the error the evaluator would report for indexing a
+// value of type left with one of type index.
+func indexError(left, index typ) string {
+	switch left.name {
+	case "":
+		return ""
+	case "array", "string":
+		if index.name == "" || index.name == "int" {
+			return ""
+		}
+	case "hash":
+		return ""
+	case "module":
+		if index.name == "" || index.name == "string" {
+			return ""
+		}
+	}
+	return fmt.Sprintf("index operator not supported: %s", left.runtimeName())
+}
+
+// sliceError returns the runtime error slicing left between bounds would
+// raise, or "" if it may succeed.
+func sliceError(left typ, bounds ...typ) string {
+	switch left.name {
+	case "", "array", "string":
+	default:
+		return fmt.Sprintf("slice operator not supported: %s", left.runtimeName())
+	}
+	for _, bound := range bounds {
+		if bound.name != "" && bound.name != "int" && bound.name != "null" {
+			return fmt.Sprintf("slice bound must be INTEGER, got %s", bound.runtimeName())
+		}
+	}
+	return ""
+}
+
+// builtinSignature describes a builtin for the checker: its number of
+// arguments (-1 for any), a check of the i-th argument's type returning the
+// builtin's error for it, and its result type.
+type builtinSignature struct {
+	arity  int
+	check  func(i int, arg typ) string
+	result typ
+}
+
+var builtinSignatures = map[string]builtinSignature{
+	"len": {
+		arity: 1,
+		check: func(i int, arg typ) string {
+			if arg.name == "" || arg.name == "string" || arg.name == "array" || arg.name == "hash" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `len` not supported, got %s", arg.runtimeName())
+		},
+		result: intType,
+	},
+	"push": {
+		arity: 2,
+		check: func(i int, arg typ) string {
+			if i > 0 || arg.name == "" || arg.name == "array" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `push` must be ARRAY, got %s", arg.runtimeName())
+		},
+		result: arrayType,
+	},
+	"freeze": {
+		arity: 1,
+		check: func(i int, arg typ) string {
+			if arg.name == "" || arg.name == "array" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `freeze` must be ARRAY, got %s", arg.runtimeName())
+		},
+		result: arrayType,
+	},
+	"spit": {
+		arity:  -1,
+		result: nullType,
+	},
+	"map":        arrayBuiltin("map", 2, 1, arrayType),
+	"filter":     arrayBuiltin("filter", 2, 1, arrayType),
+	"reduce":     arrayBuiltin("reduce", -1, 1, dynamic),
+	"each":       arrayBuiltin("each", 2, 1, nullType),
+	"find":       arrayBuiltin("find", 2, 1, dynamic),
+	"any":        arrayBuiltin("any", 2, 1, boolType),
+	"all":        arrayBuiltin("all", 2, 1, boolType),
+	"sort":       arrayBuiltin("sort", -1, 1, arrayType),
+	"reverse":    arrayBuiltin("reverse", 1, -1, arrayType),
+	"first":      arrayBuiltin("first", 1, -1, dynamic),
+	"last":       arrayBuiltin("last", 1, -1, dynamic),
+	"rest":       arrayBuiltin("rest", 1, -1, dynamic),
+	"slice":      arrayBuiltin("slice", -1, -1, arrayType),
+	"flatten":    arrayBuiltin("flatten", 1, -1, arrayType),
+	"unique":     arrayBuiltin("unique", 1, -1, arrayType),
+	"concat":     arraysBuiltin("concat", -1),
+	"zip":        arraysBuiltin("zip", 2),
+	"keys":       hashBuiltin("keys", 1, arrayType),
+	"values":     hashBuiltin("values", 1, arrayType),
+	"entries":    hashBuiltin("entries", 1, arrayType),
+	"has":        hashBuiltin("has", 2, boolType),
+	"get":        hashBuiltin("get", -1, dynamic),
+	"delete":     hashBuiltin("delete", 2, hashType),
+	"type":       {arity: 1, result: stringType},
+	"str":        {arity: 1, result: stringType},
+	"repr":       {arity: 1, result: stringType},
+	"bool":       {arity: 1, result: boolType},
+	"isFunction": {arity: 1, result: boolType},
+	"callable":   {arity: 1, result: boolType},
+	"format":     formatter("format", stringType),
+	"printf":     formatter("printf", nullType),
+	"print":      {arity: -1, result: nullType},
+	"int":        conversion("int", intType),
+	"float":      conversion("float", floatType),
+	"merge": {
+		arity: -1,
+		check: func(i int, arg typ) string {
+			if arg.name == "" || arg.name == "hash" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `merge` must be HASH, got %s", arg.runtimeName())
+		},
+		result: hashType,
+	},
+}
+
+// arrayBuiltin is the signature of a builtin whose first argument is an
+// array and whose argument at index fn, unless it is -1, is a function.
+func arrayBuiltin(name string, arity, fn int, result typ) builtinSignature {
+	return builtinSignature{
+		arity: arity,
+		check: func(i int, arg typ) string {
+			switch {
+			case i == 0 && arg.name != "" && arg.name != "array":
+				return fmt.Sprintf("argument to `%s` must be ARRAY, got %s", name, arg.runtimeName())
+			case i == fn && arg.name != "" && arg.name != "fun":
+				return fmt.Sprintf("argument to `%s` must be FUNCTION, got %s", name, arg.runtimeName())
+			}
+			return ""
+		},
+		result: result,
+	}
+}
+
+// hashBuiltin is the signature of a builtin whose first argument is a hash.
+func hashBuiltin(name string, arity int, result typ) builtinSignature {
+	return builtinSignature{
+		arity: arity,
+		check: func(i int, arg typ) string {
+			if i > 0 || arg.name == "" || arg.name == "hash" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `%s` must be HASH, got %s", name, arg.runtimeName())
+		},
+		result: result,
+	}
+}
+
+// formatter is the signature of a builtin taking a format string and the
+// values for its verbs.
+func formatter(name string, result typ) builtinSignature {
+	return builtinSignature{
+		arity: -1,
+		check: func(i int, arg typ) string {
+			if i > 0 || arg.name == "" || arg.name == "string" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `%s` must be STRING, got %s", name, arg.runtimeName())
+		},
+		result: result,
+	}
+}
+
+// conversion is the signature of a builtin converting numbers, booleans and
+// strings to typ.
+func conversion(name string, result typ) builtinSignature {
+	return builtinSignature{
+		arity: 1,
+		check: func(i int, arg typ) string {
+			switch arg.name {
+			case "", "int", "float", "bool", "string":
+				return ""
+			}
+			return fmt.Sprintf("argument to `%s` not supported, got %s", name, arg.runtimeName())
+		},
+		result: result,
+	}
+}
+
+// arraysBuiltin is the signature of a builtin whose arguments are all arrays.
+func arraysBuiltin(name string, arity int) builtinSignature {
+	return builtinSignature{
+		arity: arity,
+		check: func(i int, arg typ) string {
+			if arg.name == "" || arg.name == "array" {
+				return ""
+			}
+			return fmt.Sprintf("argument to `%s` must be ARRAY, got %s", name, arg.runtimeName())
+		},
+		result: arrayType,
+	}
+}
@@ -0,0 +1,96 @@
+package checker
+
+import (
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`"a" - 1`, []string{"1:5: error: type mismatch: STRING - INTEGER"}},
+		{`"a" * "b"`, []string{"1:5: error: unknown operator: STRING * STRING"}},
+		{`"a" ** 2`, []string{"1:5: error: type mismatch: STRING ** INTEGER"}},
+		{`lit n: int = 2 ** 3; n`, nil},
+		{`yea + nah`, []string{"1:5: error: unknown operator: BOOLEAN + BOOLEAN"}},
+		{`-"a"`, []string{"1:1: error: unknown operator: -STRING"}},
+		{`lit s = "a"; s + 1`, []string{"1:16: error: type mismatch: STRING + INTEGER"}},
+		{`lit n = len("abc"); n + "!"`, []string{"1:23: error: type mismatch: INTEGER + STRING"}},
+		{`len(5)`, []string{"1:5: error: argument to `len` not supported, got INTEGER"}},
+		{`push("a", 1)`, []string{"1:6: error: argument to `push` must be ARRAY, got STRING"}},
+		{`len([1], 2)`, []string{"1:1: error: wrong number of arguments to len. got=2, want=1"}},
+		{`lit x: int = "five";`, []string{"1:5: error: cannot use string as int in lit x"}},
+		{`lit x: float = 5; x`, nil},
+		{`lit x: number = 5; x`, []string{"1:8: error: unknown type number"}},
+		{
+			"lit add = fun(a: int, b: int) -> int { a + b };\nadd(1, \"2\");",
+			[]string{"2:8: error: cannot use string as int in argument 2 to add"},
+		},
+		{
+			`lit f = fun(a: string) -> int { a };`,
+			[]string{"1:33: error: cannot return string from a function returning int"},
+		},
+		{
+			`lit f = fun(a: int) -> string { return a + 1; };`,
+			[]string{"1:33: error: cannot return int from a function returning string"},
+		},
+		{
+			`lit f = fun(a: int) -> int { a * 2 }; f(2) + "x"`,
+			[]string{"1:44: error: type mismatch: INTEGER + STRING"},
+		},
+		{`lit five = 5; five(1)`, []string{"1:15: error: not a function: INTEGER"}},
+		{`[1, 2]["a"]`, []string{"1:7: error: index operator not supported: ARRAY"}},
+		{`lit n = 1; n[0]`, []string{"1:13: error: index operator not supported: INTEGER"}},
+		{`"abc"[0] + 1`, []string{"1:10: error: type mismatch: STRING + INTEGER"}},
+		{`"abc"[1:] - 1`, []string{"1:11: error: type mismatch: STRING - INTEGER"}},
+		{`5[1:2]`, []string{"1:2: error: slice operator not supported: INTEGER"}},
+		{`[1, 2][:"a"]`, []string{"1:7: error: slice bound must be INTEGER, got STRING"}},
+		{`lit s = "ab"; s[0:len(s)]`, nil},
+		{`keys([1])`, []string{"1:6: error: argument to `keys` must be HASH, got ARRAY"}},
+		{`merge({}, {"a": 1}, 2)`, []string{"1:21: error: argument to `merge` must be HASH, got INTEGER"}},
+		{`len({}) + has({}, 1)`, []string{"1:9: error: type mismatch: INTEGER + BOOLEAN"}},
+		{`format(1, 2)`, []string{"1:8: error: argument to `format` must be STRING, got INTEGER"}},
+		{`format("%d", 1) - 1`, []string{"1:17: error: type mismatch: STRING - INTEGER"}},
+		{`lit name = "x"; "hi ${name}" - 1`, []string{"1:30: error: type mismatch: STRING - INTEGER"}},
+		{`"${undefinedName}"`, []string{"1:4: error: undefined identifier: undefinedName"}},
+		{`int([1])`, []string{"1:5: error: argument to `int` not supported, got ARRAY"}},
+		{`int("4") + float(2) + str(1)`, []string{"1:21: error: type mismatch: FLOAT + STRING"}},
+		{`lit t: string = type(1); lit b: bool = callable(len);`, nil},
+		{`map(1, len)`, []string{"1:5: error: argument to `map` must be ARRAY, got INTEGER"}},
+		{`filter([1], 2)`, []string{"1:13: error: argument to `filter` must be FUNCTION, got INTEGER"}},
+		{`concat([1], "a")`, []string{"1:13: error: argument to `concat` must be ARRAY, got STRING"}},
+		{`any([1], len) + 1`, []string{"1:15: error: type mismatch: BOOLEAN + INTEGER"}},
+		{`sort([2, 1], fun(a, b) { a < b }); reduce([1], fun(a, b) { a + b }, 0)`, nil},
+		{`fr (yea) { 1 } lowkey { 2 } + "s"`, []string{"1:29: error: type mismatch: INTEGER + STRING"}},
+		// Unannotated and rebound names are dynamic.
+		{`lit f = fun(a, b) { a - b }; f("x", 1)`, nil},
+		{`lit x = 1; lit x = "one"; x - 1`, nil},
+		{`lit g = fun(x) { x }; g(1) + "a"`, nil},
+		{`fr (yea) { 1 } + "s"`, nil},
+		{`"a" == 1`, nil},
+		{`{"a": 1}["a"] + 1`, nil},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		diagnostics := Check(program)
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. expected=%q, got=%v", tt.input, tt.expected, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic %d for %q. expected=%q, got=%q", i, tt.input, tt.expected[i], d)
+			}
+		}
+	}
+}
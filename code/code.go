@@ -0,0 +1,215 @@
+// Package code defines the bytecode instruction set shared by the compiler and the virtual machine.
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a flat sequence of encoded opcodes and their operands.
+type Instructions []byte
+
+// Opcode identifies a single VM instruction.
+type Opcode byte
+
+const (
+	OpConstant       Opcode = iota // OpConstant pushes the constant at the operand index.
+	OpPop                          // OpPop discards the top of the stack.
+	OpAdd                          // OpAdd pops two values and pushes their sum.
+	OpSub                          // OpSub pops two values and pushes their difference.
+	OpMul                          // OpMul pops two values and pushes their product.
+	OpDiv                          // OpDiv pops two values and pushes their quotient.
+	OpTrue                         // OpTrue pushes yea.
+	OpFalse                        // OpFalse pushes nah.
+	OpNull                         // OpNull pushes null.
+	OpEqual                        // OpEqual pops two values and pushes whether they are equal.
+	OpNotEqual                     // OpNotEqual pops two values and pushes whether they differ.
+	OpGreaterThan                  // OpGreaterThan pops two values and pushes left > right.
+	OpLessThan                     // OpLessThan pops two values and pushes left < right.
+	OpMinus                        // OpMinus negates the top of the stack.
+	OpBang                         // OpBang logically negates the top of the stack.
+	OpJump                         // OpJump jumps to the operand offset.
+	OpJumpNotTruthy                // OpJumpNotTruthy pops a value and jumps to the operand offset if it is falsy.
+	OpGetGlobal                    // OpGetGlobal pushes the global at the operand index, falling back to the builtin of the same name.
+	OpSetGlobal                    // OpSetGlobal pops a value into the global at the operand index.
+	OpGetLocal                     // OpGetLocal pushes the local at the operand index, which is unset until assigned.
+	OpSetLocal                     // OpSetLocal pops a value into the local at the operand index.
+	OpGetFree                      // OpGetFree pushes the free variable at the operand index of the current closure.
+	OpCurrentClosure               // OpCurrentClosure pushes the closure being executed, for recursion.
+	OpArray                        // OpArray builds an array from the operand count of stack values.
+	OpHash                         // OpHash builds a hash from the operand count of stack values (keys and values).
+	OpIndex                        // OpIndex pops an index and a collection and pushes the element.
+	OpMember                       // OpMember pops a value and pushes its member named by the string constant operand.
+	OpCall                         // OpCall calls the function below the operand count of arguments.
+	OpReturnValue                  // OpReturnValue returns the top of the stack from the current function.
+	OpReturn                       // OpReturn returns null from the current function.
+	OpClosure                      // OpClosure wraps the function constant (first operand) with the given count of free variables.
+	OpImport                       // OpImport pushes the module at the path held by the string constant operand.
+	OpSlice                        // OpSlice pops an end, a start and a collection and pushes the slice between them; null bounds are omitted.
+	OpConcat                       // OpConcat joins the operand count of stack values into a string, as template strings do.
+	OpPow                          // OpPow pops an exponent and a base and pushes the power.
+	OpMakeCell                     // OpMakeCell moves the local at the operand index into a new cell, for closures to share.
+	OpGetCell                      // OpGetCell pushes the value of the cell in the local at the operand index.
+	OpSetCell                      // OpSetCell pops a value into the cell in the local at the operand index.
+	OpGetFreeCell                  // OpGetFreeCell pushes the cell of the free variable at the operand index, to pass it on to a closure.
+	OpJumpIfSet                    // OpJumpIfSet jumps to the operand offset if the top of the stack holds a value, and pops it otherwise.
+)
+
+// Definition describes an opcode's name and the byte width of each operand.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:       {"OpConstant", []int{2}},
+	OpPop:            {"OpPop", []int{}},
+	OpAdd:            {"OpAdd", []int{}},
+	OpSub:            {"OpSub", []int{}},
+	OpMul:            {"OpMul", []int{}},
+	OpDiv:            {"OpDiv", []int{}},
+	OpTrue:           {"OpTrue", []int{}},
+	OpFalse:          {"OpFalse", []int{}},
+	OpNull:           {"OpNull", []int{}},
+	OpEqual:          {"OpEqual", []int{}},
+	OpNotEqual:       {"OpNotEqual", []int{}},
+	OpGreaterThan:    {"OpGreaterThan", []int{}},
+	OpLessThan:       {"OpLessThan", []int{}},
+	OpMinus:          {"OpMinus", []int{}},
+	OpBang:           {"OpBang", []int{}},
+	OpJump:           {"OpJump", []int{2}},
+	OpJumpNotTruthy:  {"OpJumpNotTruthy", []int{2}},
+	OpGetGlobal:      {"OpGetGlobal", []int{2}},
+	OpSetGlobal:      {"OpSetGlobal", []int{2}},
+	OpGetLocal:       {"OpGetLocal", []int{1}},
+	OpSetLocal:       {"OpSetLocal", []int{1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpArray:          {"OpArray", []int{2}},
+	OpHash:           {"OpHash", []int{2}},
+	OpIndex:          {"OpIndex", []int{}},
+	OpMember:         {"OpMember", []int{2}},
+	OpCall:           {"OpCall", []int{1}},
+	OpReturnValue:    {"OpReturnValue", []int{}},
+	OpReturn:         {"OpReturn", []int{}},
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpImport:         {"OpImport", []int{2}},
+	OpSlice:          {"OpSlice", []int{}},
+	OpConcat:         {"OpConcat", []int{2}},
+	OpPow:            {"OpPow", []int{}},
+	OpMakeCell:       {"OpMakeCell", []int{1}},
+	OpGetCell:        {"OpGetCell", []int{1}},
+	OpSetCell:        {"OpSetCell", []int{1}},
+	OpGetFreeCell:    {"OpGetFreeCell", []int{1}},
+	OpJumpIfSet:      {"OpJumpIfSet", []int{2}},
+}
+
+// Lookup returns the definition of op.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes op and its operands as a single instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction described by def and
+// returns them along with the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint16 decodes a big-endian two byte operand.
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 decodes a one byte operand.
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
+
+// String disassembles the instructions, one per line, prefixed by their offset.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+}
@@ -0,0 +1,42 @@
+package code
+
+import "sort"
+
+// LineEntry maps the instructions from Offset up to the next entry's offset to
+// a source line.
+type LineEntry struct {
+	Offset int
+	Line   int
+}
+
+// LineTable is the debug line table of one function, ordered by offset. It only
+// has an entry where the source line changes.
+type LineTable []LineEntry
+
+// Add records that the instruction at offset comes from line. Entries must be
+// added in offset order; unknown lines (0) are ignored.
+func (lt LineTable) Add(offset, line int) LineTable {
+	if line == 0 || (len(lt) > 0 && lt[len(lt)-1].Line == line) {
+		return lt
+	}
+	if len(lt) > 0 && lt[len(lt)-1].Offset == offset {
+		lt[len(lt)-1].Line = line
+		return lt
+	}
+	return append(lt, LineEntry{Offset: offset, Line: line})
+}
+
+// Truncate drops the entries for instructions at or after offset.
+func (lt LineTable) Truncate(offset int) LineTable {
+	i := sort.Search(len(lt), func(i int) bool { return lt[i].Offset >= offset })
+	return lt[:i]
+}
+
+// Line returns the source line of the instruction at offset, or 0 if unknown.
+func (lt LineTable) Line(offset int) int {
+	i := sort.Search(len(lt), func(i int) bool { return lt[i].Offset > offset })
+	if i == 0 {
+		return 0
+	}
+	return lt[i-1].Line
+}
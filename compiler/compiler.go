@@ -0,0 +1,650 @@
+// Package compiler translates an ast.Program into bytecode for the vm package.
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Compiler holds the constant pool, the symbol table and one instruction buffer
+// per function currently being compiled.
+type Compiler struct {
+	constants []object.Object
+	names     map[string]int // string constants already added for member names and import paths
+
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+
+	line int // source line of the node being compiled, recorded in the line tables
+}
+
+// EmittedInstruction remembers an emitted opcode and where it starts.
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// CompilationScope is the instruction buffer of one function.
+type CompilationScope struct {
+	instructions        code.Instructions
+	lines               code.LineTable
+	lastInstruction     EmittedInstruction
+	previousInstruction EmittedInstruction
+}
+
+// Bytecode is the result of compiling a program.
+type Bytecode struct {
+	Instructions code.Instructions
+	Lines        code.LineTable // source lines of the main program's instructions
+	Constants    []object.Object
+	GlobalNames  []string // names of the global slots, in slot order
+}
+
+// New returns a Compiler with an empty global scope.
+func New() *Compiler {
+	return NewWithState(NewSymbolTable(), []object.Object{})
+}
+
+// NewWithState returns a Compiler that continues from the symbols and constants
+// of earlier compilations, as the REPL does between lines.
+func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
+	mainScope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+
+	return &Compiler{
+		constants:   constants,
+		names:       make(map[string]int),
+		symbolTable: s,
+		scopes:      []CompilationScope{mainScope},
+	}
+}
+
+// Compile compiles node into the current scope.
+func (c *Compiler) Compile(node ast.Node) error {
+	if line := lineOf(node); line > 0 {
+		defer func(outer int) { c.line = outer }(c.line)
+		c.line = line
+	}
+
+	switch node := node.(type) {
+	case *ast.Program:
+		return c.compileProgram(node)
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			if err := c.Compile(s); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		return c.compileLetStatement(node)
+
+	case *ast.ReturnStatement:
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	case *ast.ImportStatement:
+		c.emit(code.OpImport, c.nameConstant(node.Path.Value))
+
+		name := strings.TrimSuffix(filepath.Base(node.Path.Value), ".zzz")
+		if node.Alias != nil {
+			name = node.Alias.Value
+		}
+		c.storeSymbol(c.symbolTable.Define(name))
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "+":
+			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			c.emit(code.OpDiv)
+		case "**":
+			c.emit(code.OpPow)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.Identifier:
+		c.loadName(node.Value)
+
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := c.Compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Pairs)*2)
+
+	case *ast.TemplateLiteral:
+		count := 0
+		for i, text := range node.Texts {
+			if text != "" {
+				c.emit(code.OpConstant, c.addConstant(&object.String{Value: text}))
+				count++
+			}
+			if i < len(node.Exprs) {
+				if err := c.Compile(node.Exprs[i]); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		c.emit(code.OpConcat, count)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+
+	case *ast.SliceExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		for _, bound := range []ast.Expression{node.Start, node.End} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSlice)
+
+	case *ast.MemberExpression:
+		if err := c.Compile(node.Object); err != nil {
+			return err
+		}
+		c.emit(code.OpMember, c.nameConstant(node.Property.Value))
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node, "")
+
+	case *ast.CallExpression:
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+		for _, a := range node.Arguments {
+			if err := c.Compile(a); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return fmt.Errorf("cannot compile %T", node)
+	}
+
+	return nil
+}
+
+// compileProgram compiles the top-level statements so that the main function
+// returns the value of the last expression statement, like evaluator.Eval.
+func (c *Compiler) compileProgram(program *ast.Program) error {
+	// Globals are visible to every function in the program, including ones
+	// defined before the binding, so reserve their slots up front.
+	for _, s := range program.Statements {
+		if let, ok := s.(*ast.LetStatement); ok {
+			c.symbolTable.Define(let.Name.Value)
+		}
+	}
+
+	for _, s := range program.Statements {
+		if err := c.Compile(s); err != nil {
+			return err
+		}
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileLetStatement(node *ast.LetStatement) error {
+	name := node.Name.Value
+
+	if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+		if err := c.compileFunctionLiteral(fn, name); err != nil {
+			return err
+		}
+	} else if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	// The slot was declared with the function, but is unset until here, so
+	// `lit x = x + 1` reads the outer x.
+	c.storeSymbol(c.symbolTable.Define(name))
+	return nil
+}
+
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// Emit an `OpJumpNotTruthy` with a bogus value, patched below.
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlockValue(node.Consequence); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else if err := c.compileBlockValue(node.Alternative); err != nil {
+		return err
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileBlockValue compiles a block so that it leaves its value on the stack.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if len(block.Statements) > 0 && c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral, name string) error {
+	c.enterScope()
+
+	if name != "" {
+		c.symbolTable.DefineFunctionName(name)
+	}
+
+	// Declare every local up front, as the evaluator's frames do, so that a
+	// closure refers to a local assigned after it was created.
+	locals := []string{}
+	for _, p := range node.Parameters {
+		c.symbolTable.DefineParameter(p.Value)
+		locals = append(locals, p.Value)
+	}
+	ast.Declarations(node.Body, func(name string) {
+		c.symbolTable.Define(name)
+		locals = append(locals, name)
+	})
+
+	captured := capturedNames(node.Body)
+	for _, name := range locals {
+		if captured[name] && c.symbolTable.store[name].Scope == LocalScope {
+			c.emit(code.OpMakeCell, c.symbolTable.DefineCell(name).Index)
+		}
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.replaceLastPopWithReturn()
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	freeSymbols := c.symbolTable.FreeSymbols
+	numLocals := c.symbolTable.numDefinitions
+	lines := c.scopes[c.scopeIndex].lines
+	instructions := c.leaveScope()
+
+	for _, s := range freeSymbols {
+		if err := c.loadCell(s); err != nil {
+			return err
+		}
+	}
+
+	compiledFn := &object.CompiledFunction{
+		Instructions:  instructions,
+		Lines:         lines,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+		Name:          name,
+		Source:        object.FunctionSource(node.Parameters, node.Body),
+	}
+
+	fnIndex := c.addConstant(compiledFn)
+	c.emit(code.OpClosure, fnIndex, len(freeSymbols))
+	return nil
+}
+
+// Bytecode returns the compiled main function and its constants.
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Lines:        c.scopes[c.scopeIndex].lines,
+		Constants:    c.constants,
+		GlobalNames:  c.symbolTable.GlobalNames(),
+	}
+}
+
+// SymbolTable returns the compiler's current symbol table.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// nameConstant returns the index of a string constant holding name, adding it once.
+func (c *Compiler) nameConstant(name string) int {
+	if idx, ok := c.names[name]; ok {
+		return idx
+	}
+	idx := c.addConstant(&object.String{Value: name})
+	c.names[name] = idx
+	return idx
+}
+
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.lines = scope.lines.Add(pos, c.line)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	updatedInstructions := append(c.currentInstructions(), ins...)
+
+	c.scopes[c.scopeIndex].instructions = updatedInstructions
+
+	return posNewInstruction
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := EmittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	old := c.currentInstructions()
+	new := old[:last.Position]
+
+	c.scopes[c.scopeIndex].instructions = new
+	c.scopes[c.scopeIndex].lines = c.scopes[c.scopeIndex].lines.Truncate(last.Position)
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+
+	for i := 0; i < len(newInstruction); i++ {
+		ins[pos+i] = newInstruction[i]
+	}
+}
+
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, operand)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) enterScope() {
+	scope := CompilationScope{
+		instructions:        code.Instructions{},
+		lastInstruction:     EmittedInstruction{},
+		previousInstruction: EmittedInstruction{},
+	}
+	c.scopes = append(c.scopes, scope)
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case CellScope:
+		c.emit(code.OpGetCell, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case CellScope:
+		c.emit(code.OpSetCell, s.Index)
+	default:
+		c.emit(code.OpSetLocal, s.Index)
+	}
+}
+
+// loadName loads the value of name. A local that is not assigned yet falls
+// back to the next binding ResolveAll found, like the evaluator's lookup by
+// name does.
+func (c *Compiler) loadName(name string) {
+	symbols := c.symbolTable.ResolveAll(name)
+
+	var jumps []int
+	for i, s := range symbols {
+		c.loadSymbol(s)
+		if i < len(symbols)-1 {
+			// Emit an `OpJumpIfSet` with a bogus value, patched below.
+			jumps = append(jumps, c.emit(code.OpJumpIfSet, 9999))
+		}
+	}
+	for _, pos := range jumps {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+}
+
+// loadCell pushes the cell of a captured local, for OpClosure to hand to the
+// closure.
+func (c *Compiler) loadCell(s Symbol) error {
+	switch s.Scope {
+	case CellScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFreeCell, s.Index)
+	default:
+		return fmt.Errorf("%s captured from %s scope", s.Name, s.Scope)
+	}
+	return nil
+}
+
+// capturedNames returns the names used inside the functions nested in body.
+// The locals of body's function with these names are kept in cells.
+func capturedNames(body *ast.BlockStatement) map[string]bool {
+	names := map[string]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if fn, ok := n.(*ast.FunctionLiteral); ok {
+			ast.Inspect(fn, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Identifier); ok {
+					names[id.Value] = true
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// lineOf returns the source line of the nodes that start a statement or can
+// fail at run time, and 0 for the others, which inherit their parent's line.
+func lineOf(node ast.Node) int {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		return node.Token.Line
+	case *ast.LetStatement:
+		return node.Token.Line
+	case *ast.ReturnStatement:
+		return node.Token.Line
+	case *ast.ImportStatement:
+		return node.Token.Line
+	case *ast.PrefixExpression:
+		return node.Token.Line
+	case *ast.InfixExpression:
+		return node.Token.Line
+	case *ast.IfExpression:
+		return node.Token.Line
+	case *ast.Identifier:
+		return node.Token.Line
+	case *ast.CallExpression:
+		return node.Token.Line
+	case *ast.IndexExpression:
+		return node.Token.Line
+	case *ast.SliceExpression:
+		return node.Token.Line
+	case *ast.TemplateLiteral:
+		return node.Token.Line
+	case *ast.MemberExpression:
+		return node.Token.Line
+	case *ast.FunctionLiteral:
+		return node.Token.Line
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Disassemble writes a listing of the main program followed by every function
+// constant. Each instruction is shown with its offset, its source line (or "|"
+// when unchanged from the previous instruction), its decoded operands and,
+// where an operand refers to the constant pool or a global, what it refers to:
+//
+//	== main ==
+//	0000    1 OpConstant 0         ; 5
+//	0003    | OpSetGlobal 0        ; five
+func (b *Bytecode) Disassemble(w io.Writer) error {
+	d := &disassembler{w: w, bytecode: b}
+
+	d.function("main", b.Instructions, b.Lines)
+	for i, constant := range b.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			header := fmt.Sprintf("constant %d: %s (params=%d, locals=%d)", i, describeFunction(fn), fn.NumParameters, fn.NumLocals)
+			d.function(header, fn.Instructions, fn.Lines)
+		}
+	}
+	return d.err
+}
+
+type disassembler struct {
+	w        io.Writer
+	bytecode *Bytecode
+	err      error
+}
+
+func (d *disassembler) printf(format string, a ...any) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, a...)
+	}
+}
+
+func (d *disassembler) function(header string, ins code.Instructions, lines code.LineTable) {
+	d.printf("== %s ==\n", header)
+
+	lastLine := -1
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			d.printf("%04d ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+
+		line := "|"
+		if l := lines.Line(i); l != lastLine {
+			line = fmt.Sprint(l)
+			lastLine = l
+		}
+
+		text := def.Name
+		for _, operand := range operands {
+			text += fmt.Sprintf(" %d", operand)
+		}
+
+		if comment := d.comment(code.Opcode(ins[i]), operands); comment != "" {
+			d.printf("%04d %4s %-20s ; %s\n", i, line, text, comment)
+		} else {
+			d.printf("%04d %4s %s\n", i, line, text)
+		}
+
+		i += 1 + read
+	}
+	d.printf("\n")
+}
+
+// comment describes what the operands of an instruction refer to.
+func (d *disassembler) comment(op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant, code.OpMember, code.OpImport:
+		return d.constant(operands[0])
+	case code.OpClosure:
+		return fmt.Sprintf("%s, %d free", d.constant(operands[0]), operands[1])
+	case code.OpGetGlobal, code.OpSetGlobal:
+		if operands[0] < len(d.bytecode.GlobalNames) {
+			return d.bytecode.GlobalNames[operands[0]]
+		}
+		return "?"
+	}
+	return ""
+}
+
+func (d *disassembler) constant(index int) string {
+	if index >= len(d.bytecode.Constants) {
+		return "?"
+	}
+
+	switch constant := d.bytecode.Constants[index].(type) {
+	case *object.String:
+		return fmt.Sprintf("%q", constant.Value)
+	case *object.CompiledFunction:
+		return describeFunction(constant)
+	default:
+		return constant.Inspect()
+	}
+}
+
+func describeFunction(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "fun <anonymous>"
+	}
+	return "fun " + fn.Name
+}
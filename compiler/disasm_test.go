@@ -0,0 +1,55 @@
+package compiler
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestDisassembleGolden compiles every testdata/disasm/*.zzz program and
+// compares its listing with the .golden file next to it. Run the tests with
+// -update after an intended change in code generation.
+func TestDisassembleGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "disasm", "*.zzz"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no golden inputs found: %v", err)
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compiler := New()
+		if err := compiler.Compile(parse(string(src))); err != nil {
+			t.Fatalf("%s: compiler error: %s", path, err)
+		}
+
+		var out bytes.Buffer
+		if err := compiler.Bytecode().Disassemble(&out); err != nil {
+			t.Fatalf("%s: Disassemble returned error: %s", path, err)
+		}
+
+		golden := strings.TrimSuffix(path, ".zzz") + ".golden"
+		if *update {
+			if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%s: %s (run with -update to create it)", path, err)
+		}
+		if out.String() != string(expected) {
+			t.Errorf("%s: listing differs from %s.\nwant:\n%s\ngot:\n%s", path, golden, expected, out.String())
+		}
+	}
+}
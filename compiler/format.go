@@ -0,0 +1,468 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// The binary format of a compiled program (a .zzzc file) is:
+//
+//	magic     "ZZZC"
+//	version   uint16, big endian
+//	main      function
+//	globals   count, then each name as a string
+//	constants count, then each as a tag byte and its payload
+//
+// Counts, lengths and integers are varints. A string is its length followed by
+// its bytes, and a function is its name, its source, its local and parameter
+// counts, its instructions as a length-prefixed byte string and its line table
+// as a count of (offset, line) pairs. Function constants reference nested functions by
+// their index in the same pool, exactly like OpClosure does.
+//
+// FormatVersion goes up whenever the encoding or the instruction set changes,
+// including every new opcode, so a VM never runs a file it would misread.
+const (
+	Magic         = "ZZZC"
+	FormatVersion = 4
+)
+
+const (
+	tagInteger  byte = 1
+	tagString   byte = 2
+	tagFunction byte = 3
+)
+
+// ErrNotBytecode reports data that does not start with the compiled program magic.
+var ErrNotBytecode = errors.New("not a compiled zzz program")
+
+// VersionError reports a compiled program written for another FormatVersion.
+type VersionError struct {
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("compiled program has bytecode version %d, this build runs version %d", e.Version, FormatVersion)
+}
+
+// MarshalBinary encodes the bytecode in the .zzzc format.
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	out := []byte(Magic)
+	out = binary.BigEndian.AppendUint16(out, FormatVersion)
+
+	out = appendFunction(out, &object.CompiledFunction{Instructions: b.Instructions, Lines: b.Lines})
+
+	out = binary.AppendUvarint(out, uint64(len(b.GlobalNames)))
+	for _, name := range b.GlobalNames {
+		out = appendString(out, name)
+	}
+
+	out = binary.AppendUvarint(out, uint64(len(b.Constants)))
+	for i, constant := range b.Constants {
+		switch constant := constant.(type) {
+		case *object.Integer:
+			out = append(out, tagInteger)
+			out = binary.AppendVarint(out, constant.Value)
+		case *object.String:
+			out = append(out, tagString)
+			out = appendString(out, constant.Value)
+		case *object.CompiledFunction:
+			out = append(out, tagFunction)
+			out = appendFunction(out, constant)
+		default:
+			return nil, fmt.Errorf("constant %d: cannot encode %s", i, constant.Type())
+		}
+	}
+
+	return out, nil
+}
+
+func appendString(out []byte, s string) []byte {
+	out = binary.AppendUvarint(out, uint64(len(s)))
+	return append(out, s...)
+}
+
+func appendFunction(out []byte, fn *object.CompiledFunction) []byte {
+	out = appendString(out, fn.Name)
+	out = appendString(out, fn.Source)
+	out = binary.AppendUvarint(out, uint64(fn.NumLocals))
+	out = binary.AppendUvarint(out, uint64(fn.NumParameters))
+	out = appendString(out, string(fn.Instructions))
+
+	out = binary.AppendUvarint(out, uint64(len(fn.Lines)))
+	for _, entry := range fn.Lines {
+		out = binary.AppendUvarint(out, uint64(entry.Offset))
+		out = binary.AppendUvarint(out, uint64(entry.Line))
+	}
+	return out
+}
+
+// UnmarshalBinary decodes bytecode in the .zzzc format. It returns
+// ErrNotBytecode for other data and a *VersionError for programs compiled by an
+// incompatible version. Any other data the VM could not run safely, such as
+// operands out of range, jumps between instructions or code that pops an empty
+// stack, is reported as a corrupt compiled program.
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if len(data) < len(Magic)+2 || string(data[:len(Magic)]) != Magic {
+		return ErrNotBytecode
+	}
+	if version := int(binary.BigEndian.Uint16(data[len(Magic):])); version != FormatVersion {
+		return &VersionError{Version: version}
+	}
+
+	d := &decoder{data: data[len(Magic)+2:]}
+
+	main := d.function()
+	globals := make([]string, d.count())
+	for i := range globals {
+		globals[i] = d.string()
+	}
+
+	constants := make([]object.Object, d.count())
+	for i := range constants {
+		switch tag := d.byte(); tag {
+		case tagInteger:
+			constants[i] = &object.Integer{Value: d.varint()}
+		case tagString:
+			constants[i] = &object.String{Value: d.string()}
+		case tagFunction:
+			constants[i] = d.function()
+		default:
+			d.fail("constant %d has unknown tag %d", i, tag)
+		}
+	}
+
+	if d.err == nil && len(d.data) != 0 {
+		d.fail("%d trailing bytes", len(d.data))
+	}
+	if d.err == nil {
+		d.err = checkProgram(main, constants, len(globals))
+	}
+	if d.err != nil {
+		return fmt.Errorf("corrupt compiled program: %w", d.err)
+	}
+
+	b.Instructions = main.Instructions
+	b.Lines = main.Lines
+	b.GlobalNames = globals
+	b.Constants = constants
+	return nil
+}
+
+// decoder reads the primitives of the format from data. The first error sticks
+// and makes every later read return a zero value.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+	d.data = nil
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.fail("unexpected end of data")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("malformed varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail("malformed varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// count reads a length, which can never exceed the bytes left since every
+// element takes at least one byte.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("count %d exceeds the remaining %d bytes", n, len(d.data))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.count()
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *decoder) function() *object.CompiledFunction {
+	fn := &object.CompiledFunction{
+		Name:          d.string(),
+		Source:        d.string(),
+		NumLocals:     int(d.uvarint()),
+		NumParameters: int(d.uvarint()),
+		Instructions:  code.Instructions(d.string()),
+	}
+
+	lines := make(code.LineTable, d.count())
+	for i := range lines {
+		lines[i] = code.LineEntry{Offset: int(d.uvarint()), Line: int(d.uvarint())}
+	}
+	fn.Lines = lines
+
+	if err := validate(fn.Instructions); err != nil {
+		d.fail("function %q: %s", fn.Name, err)
+	}
+	return fn
+}
+
+// validate checks that ins decodes into whole, defined instructions.
+func validate(ins code.Instructions) error {
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return fmt.Errorf("offset %d: %s", i, err)
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return fmt.Errorf("offset %d: truncated %s", i, def.Name)
+		}
+		i += 1 + width
+	}
+	return nil
+}
+
+// checkProgram checks the main function and every function constant of a
+// decoded program with checkReferences. The main function runs without locals
+// or free variables.
+func checkProgram(main *object.CompiledFunction, constants []object.Object, numGlobals int) error {
+	numFree := map[*object.CompiledFunction]int{}
+	for _, constant := range constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			numFree[fn] = freeVariables(fn)
+		}
+	}
+
+	if main.NumLocals != 0 || freeVariables(main) != 0 {
+		return errors.New("main function has locals or free variables")
+	}
+	if err := checkReferences(main, constants, numGlobals, numFree); err != nil {
+		return err
+	}
+	for _, constant := range constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			if err := checkReferences(fn, constants, numGlobals, numFree); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkReferences makes sure the operands of fn point at things that exist:
+// constants of the right kind, globals, its own locals and instructions, and,
+// for every closure fn creates, the free variables that closure reads.
+// numFree holds how many free variables each function reads.
+func checkReferences(fn *object.CompiledFunction, constants []object.Object, numGlobals int, numFree map[*object.CompiledFunction]int) error {
+	constant := func(index int, want object.ObjectType) error {
+		if index >= len(constants) {
+			return fmt.Errorf("constant %d out of range", index)
+		}
+		if want != "" && constants[index].Type() != want {
+			return fmt.Errorf("constant %d is not %s", index, want)
+		}
+		return nil
+	}
+
+	if fn.NumParameters > fn.NumLocals {
+		return fmt.Errorf("function %q has %d parameters but %d locals", fn.Name, fn.NumParameters, fn.NumLocals)
+	}
+
+	ins := fn.Instructions
+	starts := map[int]bool{}
+	for i := 0; i < len(ins); {
+		starts[i] = true
+		def, _ := code.Lookup(ins[i])
+		_, read := code.ReadOperands(def, ins[i+1:])
+		i += 1 + read
+	}
+
+	for i := 0; i < len(ins); {
+		def, _ := code.Lookup(ins[i])
+		operands, read := code.ReadOperands(def, ins[i+1:])
+
+		var err error
+		switch code.Opcode(ins[i]) {
+		case code.OpConstant:
+			err = constant(operands[0], "")
+		case code.OpMember, code.OpImport:
+			err = constant(operands[0], object.STRING_OBJ)
+		case code.OpClosure:
+			err = constant(operands[0], object.COMPILED_FUNCTION_OBJ)
+			if err == nil {
+				closed := constants[operands[0]].(*object.CompiledFunction)
+				if operands[1] < numFree[closed] {
+					err = fmt.Errorf("closure gets %d free variables but reads %d", operands[1], numFree[closed])
+				}
+			}
+		case code.OpGetGlobal, code.OpSetGlobal:
+			if operands[0] >= numGlobals {
+				err = fmt.Errorf("global %d out of range", operands[0])
+			}
+		case code.OpGetLocal, code.OpSetLocal, code.OpMakeCell, code.OpGetCell, code.OpSetCell:
+			if operands[0] >= fn.NumLocals {
+				err = fmt.Errorf("local %d out of range", operands[0])
+			}
+		case code.OpJump, code.OpJumpNotTruthy, code.OpJumpIfSet:
+			if !starts[operands[0]] {
+				err = fmt.Errorf("jump to %d is not the start of an instruction", operands[0])
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("function %q, offset %d: %s", fn.Name, i, err)
+		}
+
+		i += 1 + read
+	}
+
+	if err := checkStack(fn); err != nil {
+		return fmt.Errorf("function %q, %s", fn.Name, err)
+	}
+	return nil
+}
+
+// freeVariables returns how many free variables fn reads, one more than the
+// highest index its instructions use.
+func freeVariables(fn *object.CompiledFunction) int {
+	n := 0
+	for i := 0; i < len(fn.Instructions); {
+		def, _ := code.Lookup(fn.Instructions[i])
+		operands, read := code.ReadOperands(def, fn.Instructions[i+1:])
+		switch code.Opcode(fn.Instructions[i]) {
+		case code.OpGetFree, code.OpGetFreeCell:
+			n = max(n, operands[0]+1)
+		}
+		i += 1 + read
+	}
+	return n
+}
+
+// checkStack follows every path through fn, counting the values on the stack,
+// so that no instruction pops values that are not there, paths that meet agree
+// on the count, and no path runs past the last instruction. The jump targets
+// must already be known to start instructions.
+func checkStack(fn *object.CompiledFunction) error {
+	ins := fn.Instructions
+	if len(ins) == 0 {
+		return errors.New("no instructions")
+	}
+	heights := map[int]int{0: 0}
+	pending := []int{0}
+
+	reach := func(from, to, height int) error {
+		if to >= len(ins) {
+			return fmt.Errorf("offset %d: runs past the end", from)
+		}
+		if seen, ok := heights[to]; ok {
+			if seen != height {
+				return fmt.Errorf("offset %d: reached with %d and %d values on the stack", to, seen, height)
+			}
+			return nil
+		}
+		heights[to] = height
+		pending = append(pending, to)
+		return nil
+	}
+
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		op := code.Opcode(ins[i])
+		def, _ := code.Lookup(ins[i])
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		next := i + 1 + read
+
+		pops, pushes := stackEffect(op, operands)
+		height := heights[i]
+		if pops > height {
+			return fmt.Errorf("offset %d: %s pops %d values but the stack holds %d", i, def.Name, pops, height)
+		}
+		if op == code.OpHash && operands[0]%2 != 0 {
+			return fmt.Errorf("offset %d: OpHash of %d values, not key and value pairs", i, operands[0])
+		}
+		height += pushes - pops
+
+		var err error
+		switch op {
+		case code.OpReturnValue, code.OpReturn:
+		case code.OpJump:
+			err = reach(i, operands[0], height)
+		case code.OpJumpNotTruthy:
+			if err = reach(i, operands[0], height); err == nil {
+				err = reach(i, next, height)
+			}
+		case code.OpJumpIfSet:
+			// The value stays on the stack if it is set, and is popped if not.
+			if err = reach(i, operands[0], height); err == nil {
+				err = reach(i, next, height-1)
+			}
+		default:
+			err = reach(i, next, height)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stackEffect returns how many values op pops and pushes. OpJumpIfSet is
+// counted as it behaves when it jumps.
+func stackEffect(op code.Opcode, operands []int) (pops, pushes int) {
+	switch op {
+	case code.OpConstant, code.OpTrue, code.OpFalse, code.OpNull, code.OpGetGlobal, code.OpGetLocal,
+		code.OpGetFree, code.OpGetCell, code.OpGetFreeCell, code.OpCurrentClosure, code.OpImport:
+		return 0, 1
+	case code.OpPop, code.OpSetGlobal, code.OpSetLocal, code.OpSetCell, code.OpJumpNotTruthy, code.OpReturnValue:
+		return 1, 0
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpPow,
+		code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan, code.OpIndex:
+		return 2, 1
+	case code.OpMinus, code.OpBang, code.OpMember:
+		return 1, 1
+	case code.OpJumpIfSet:
+		return 1, 1
+	case code.OpSlice:
+		return 3, 1
+	case code.OpArray, code.OpHash, code.OpConcat, code.OpClosure:
+		return operands[len(operands)-1], 1
+	case code.OpCall:
+		return operands[0] + 1, 1
+	default:
+		return 0, 0
+	}
+}
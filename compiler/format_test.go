@@ -0,0 +1,180 @@
+package compiler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	input := `
+	lit greeting = "hi";
+	lit adder = fun(a) {
+		fun(b) { a + b - 1000000 }
+	};
+	import "std/strings" as s;
+	s.join([greeting, adder(-1)(2)], " ")`
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := compiler.Bytecode()
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned error: %s", err)
+	}
+
+	decoded := &Bytecode{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned error: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("bytecode changed in round trip.\nwant=%+v\ngot =%+v", original, decoded)
+	}
+}
+
+func TestFormatVersionCoversOpcodes(t *testing.T) {
+	// The last opcode each format version knows.
+	lastOpcodes := []code.Opcode{1: code.OpImport, 2: code.OpPow, 3: code.OpJumpIfSet, 4: code.OpJumpIfSet}
+
+	if FormatVersion >= len(lastOpcodes) {
+		t.Fatalf("no last opcode listed for FormatVersion %d", FormatVersion)
+	}
+	last := lastOpcodes[FormatVersion]
+	if def, err := code.Lookup(byte(last) + 1); err == nil {
+		t.Errorf("%s was added without bumping FormatVersion", def.Name)
+	}
+}
+
+func TestUnmarshalRejectsBadInput(t *testing.T) {
+	compiler := New()
+	compiler.Compile(parse(`lit f = fun(x) { x * 2 }; f(21)`))
+	data, _ := compiler.Bytecode().MarshalBinary()
+
+	decoded := &Bytecode{}
+	if err := decoded.UnmarshalBinary([]byte("lit x = 1;")); err != ErrNotBytecode {
+		t.Errorf("expected ErrNotBytecode. got=%v", err)
+	}
+
+	future := append([]byte{}, data...)
+	future[len(Magic)+1] = FormatVersion + 1
+	var versionErr *VersionError
+	if err := decoded.UnmarshalBinary(future); !errors.As(err, &versionErr) || versionErr.Version != FormatVersion+1 {
+		t.Errorf("expected VersionError. got=%v", err)
+	}
+
+	for n := len(Magic) + 2; n < len(data); n++ {
+		if err := decoded.UnmarshalBinary(data[:n]); err == nil {
+			t.Errorf("truncated data of %d bytes was accepted", n)
+		}
+	}
+
+	// Point the OpConstant in main at a constant that does not exist.
+	bad := &Bytecode{
+		Instructions: code.Make(code.OpConstant, 5),
+		Constants:    []object.Object{&object.Integer{Value: 1}},
+	}
+	data, _ = bad.MarshalBinary()
+	if err := decoded.UnmarshalBinary(data); err == nil {
+		t.Errorf("out of range constant was accepted")
+	}
+}
+
+func TestUnmarshalRejectsBadOperands(t *testing.T) {
+	concat := func(ins ...[]byte) code.Instructions {
+		var out code.Instructions
+		for _, i := range ins {
+			out = append(out, i...)
+		}
+		return out
+	}
+	function := func(numLocals, numParameters int, ins ...[]byte) *object.CompiledFunction {
+		return &object.CompiledFunction{Instructions: concat(ins...), NumLocals: numLocals, NumParameters: numParameters}
+	}
+	closure := concat(code.Make(code.OpClosure, 0, 0), code.Make(code.OpReturnValue))
+
+	tests := []struct {
+		name     string
+		main     code.Instructions
+		function *object.CompiledFunction
+		expected string
+	}{
+		{"local out of range", closure,
+			function(1, 0, code.Make(code.OpGetLocal, 1), code.Make(code.OpReturnValue)), "local 1 out of range"},
+		{"cell out of range", closure,
+			function(1, 1, code.Make(code.OpMakeCell, 3), code.Make(code.OpReturn)), "local 3 out of range"},
+		{"free variable not given", closure,
+			function(0, 0, code.Make(code.OpGetFree, 0), code.Make(code.OpReturnValue)), "closure gets 0 free variables but reads 1"},
+		{"parameters without locals", closure,
+			function(1, 2, code.Make(code.OpReturn)), "2 parameters but 1 locals"},
+		{"jump past the end", concat(code.Make(code.OpJump, 100), code.Make(code.OpReturn)),
+			nil, "jump to 100 is not the start of an instruction"},
+		{"jump into an operand", concat(code.Make(code.OpJump, 1), code.Make(code.OpReturn)),
+			nil, "jump to 1 is not the start of an instruction"},
+		{"running past the end", code.Make(code.OpTrue),
+			nil, "offset 0: runs past the end"},
+		{"call without a function", concat(code.Make(code.OpCall, 200), code.Make(code.OpReturnValue)),
+			nil, "OpCall pops 201 values but the stack holds 0"},
+		{"branches that disagree", concat(
+			code.Make(code.OpTrue),             // 0000
+			code.Make(code.OpJumpNotTruthy, 8), // 0001
+			code.Make(code.OpTrue),             // 0004
+			code.Make(code.OpJump, 8),          // 0005
+			code.Make(code.OpReturnValue),      // 0008
+		), nil, "offset 8: reached with"},
+		{"local in main", concat(code.Make(code.OpGetLocal, 0), code.Make(code.OpReturnValue)),
+			nil, "local 0 out of range"},
+		{"free variable in main", concat(code.Make(code.OpGetFree, 0), code.Make(code.OpReturnValue)),
+			nil, "main function has locals or free variables"},
+	}
+
+	for _, tt := range tests {
+		bad := &Bytecode{Instructions: tt.main}
+		if tt.function != nil {
+			bad.Constants = []object.Object{tt.function}
+		}
+		data, err := bad.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = (&Bytecode{}).UnmarshalBinary(data)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: expected an error containing %q. got=%v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestLineTables(t *testing.T) {
+	input := `lit a = 1;
+lit f = fun(x) {
+	x +
+		a
+};
+f(2)`
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := compiler.Bytecode()
+
+	expectedMain := code.LineTable{{Offset: 0, Line: 1}, {Offset: 6, Line: 2}, {Offset: 13, Line: 6}}
+	if !reflect.DeepEqual(bytecode.Lines, expectedMain) {
+		t.Errorf("wrong main line table. got=%v", bytecode.Lines)
+	}
+
+	fn := bytecode.Constants[1].(*object.CompiledFunction)
+	// OpGetLocal x (3), OpGetGlobal a (4), OpAdd at the operator (3), OpReturnValue.
+	expectedFn := code.LineTable{{Offset: 0, Line: 3}, {Offset: 2, Line: 4}, {Offset: 5, Line: 3}}
+	if !reflect.DeepEqual(fn.Lines, expectedFn) {
+		t.Errorf("wrong function line table. got=%v", fn.Lines)
+	}
+}
@@ -0,0 +1,193 @@
+package compiler
+
+// SymbolScope tells the compiler which instructions read and write a symbol.
+type SymbolScope string
+
+const (
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	CellScope     SymbolScope = "CELL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+// Symbol is a name resolved to a storage slot.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable maps the names visible in one function (or the global scope) to
+// their slots. Free symbols record the outer locals a closure captures.
+//
+// Locals that closures capture live in cells, which the function and its
+// closures share, so a closure sees the value the local has when the closure
+// runs rather than when it was created.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	store          map[string]Symbol
+	numDefinitions int
+	numParameters  int
+
+	FreeSymbols []Symbol
+	free        map[binding]Symbol // the free symbol of each captured binding
+}
+
+// binding identifies the symbol a table defines for name.
+type binding struct {
+	table *SymbolTable
+	name  string
+}
+
+// NewSymbolTable returns an empty global symbol table.
+func NewSymbolTable() *SymbolTable {
+	s := make(map[string]Symbol)
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free, free: map[binding]Symbol{}}
+}
+
+// NewEnclosedSymbolTable returns a symbol table for a function nested in outer.
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name to a slot in this table. Redefining a name reuses its
+// slot, so a global can be referenced before the `lit` that assigns it, and a
+// function's locals can be declared before its body is compiled.
+func (s *SymbolTable) Define(name string) Symbol {
+	if existing, ok := s.store[name]; ok && existing.Scope != FunctionScope {
+		return existing
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// DefineParameter binds name to the slot of the next parameter. Parameters
+// take the first slots in order, even when two of them share a name.
+func (s *SymbolTable) DefineParameter(name string) Symbol {
+	symbol := Symbol{Name: name, Scope: LocalScope, Index: s.numDefinitions}
+	s.store[name] = symbol
+	s.numDefinitions++
+	s.numParameters++
+	return symbol
+}
+
+// DefineFunctionName binds the name a function literal is assigned to inside
+// its own body, so it can call itself.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineCell turns the local name into a cell, for a local that closures
+// capture, and returns it. Other symbols are returned unchanged.
+func (s *SymbolTable) DefineCell(name string) Symbol {
+	symbol := s.store[name]
+	if symbol.Scope == LocalScope {
+		symbol.Scope = CellScope
+		s.store[name] = symbol
+	}
+	return symbol
+}
+
+// Resolve looks name up in this table and its outer tables, turning outer
+// locals into free symbols of this function.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	table, symbol, ok := s.lookup(s, name)
+	if !ok {
+		return symbol, false
+	}
+	return s.capture(table, symbol), true
+}
+
+// ResolveAll returns the symbols code in s reads name from, in order. The
+// first is the binding Resolve finds. While that binding is a local which may
+// not be assigned yet, the next is the binding the name has outside the
+// function, as the evaluator's lookup by name would find. A name bound nowhere
+// ends in a new global, which the VM reports as undefined unless a builtin
+// has that name.
+func (s *SymbolTable) ResolveAll(name string) []Symbol {
+	var symbols []Symbol
+	for from := s; ; {
+		table, symbol, ok := s.lookup(from, name)
+		if !ok {
+			return append(symbols, s.Global().Define(name))
+		}
+		symbols = append(symbols, s.capture(table, symbol))
+		if !table.unset(symbol) {
+			return symbols
+		}
+		from = table.Outer
+	}
+}
+
+// unset reports whether symbol, defined in s, may be read before it is
+// assigned: locals may be, while parameters always hold their arguments.
+func (s *SymbolTable) unset(symbol Symbol) bool {
+	return (symbol.Scope == LocalScope || symbol.Scope == CellScope) && symbol.Index >= s.numParameters
+}
+
+// lookup finds the innermost table from from outwards that binds name, for
+// code compiled in s. Only s itself binds the name of its function; nested
+// functions see the binding the function is assigned to instead.
+func (s *SymbolTable) lookup(from *SymbolTable, name string) (*SymbolTable, Symbol, bool) {
+	for table := from; table != nil; table = table.Outer {
+		symbol, ok := table.store[name]
+		if ok && (symbol.Scope != FunctionScope || table == s) {
+			return table, symbol, true
+		}
+	}
+	return nil, Symbol{}, false
+}
+
+// capture returns the symbol code in s uses for symbol, which table defines.
+// Every function between the two captures the binding as a free symbol.
+func (s *SymbolTable) capture(table *SymbolTable, symbol Symbol) Symbol {
+	if s == table || symbol.Scope == GlobalScope {
+		return symbol
+	}
+
+	key := binding{table, symbol.Name}
+	if free, ok := s.free[key]; ok {
+		return free
+	}
+
+	s.FreeSymbols = append(s.FreeSymbols, s.Outer.capture(table, symbol))
+	free := Symbol{Name: symbol.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.free[key] = free
+	return free
+}
+
+// Global returns the outermost table.
+func (s *SymbolTable) Global() *SymbolTable {
+	for s.Outer != nil {
+		s = s.Outer
+	}
+	return s
+}
+
+// GlobalNames returns the names of the global slots, in slot order.
+func (s *SymbolTable) GlobalNames() []string {
+	global := s.Global()
+	names := make([]string, global.numDefinitions)
+	for _, symbol := range global.store {
+		if symbol.Scope == GlobalScope {
+			names[symbol.Index] = symbol.Name
+		}
+	}
+	return names
+}
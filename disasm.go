@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/zzz"
+)
+
+// disasm prints the bytecode of a program, or of a compiled .zzzc file.
+func disasm(args []string) int {
+	flags := flag.NewFlagSet("zzz disasm", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: zzz disasm file.zzz")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	bytecode, err := zzz.CompileFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := bytecode.Disassemble(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
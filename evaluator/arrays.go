@@ -0,0 +1,374 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// The builtins below work on arrays. None of them modify the arrays they are
+// given; those that produce an array return a new one. The functions they take
+// are called through the object.Runtime, so they may be user functions or
+// builtins on either engine.
+
+func arrayMap(rt object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		elements[i] = rt.Call(fn, el)
+		if isError(elements[i]) {
+			return elements[i]
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayFilter(rt object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, el := range arr.Elements {
+		keep := rt.Call(fn, el)
+		if isError(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// arrayReduce folds the elements into an accumulator with fn(acc, el),
+// starting from the third argument or, without one, the first element.
+func arrayReduce(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	arr, fn, err := arrayAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	elements := arr.Elements
+	var acc object.Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else if len(elements) > 0 {
+		acc, elements = elements[0], elements[1:]
+	} else {
+		return newError("`reduce` of an empty ARRAY needs an initial value")
+	}
+
+	for _, el := range elements {
+		acc = rt.Call(fn, acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+func arrayEach(rt object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("each", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		if result := rt.Call(fn, el); isError(result) {
+			return result
+		}
+	}
+	return NULL
+}
+
+// arrayFind returns the first element fn is truthy for, or null.
+func arrayFind(rt object.Runtime, args ...object.Object) object.Object {
+	arr, fn, err := arrayAndFunction("find", args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		found := rt.Call(fn, el)
+		if isError(found) {
+			return found
+		}
+		if isTruthy(found) {
+			return el
+		}
+	}
+	return NULL
+}
+
+func arrayAny(rt object.Runtime, args ...object.Object) object.Object {
+	return arrayTest(rt, "any", true, args)
+}
+
+func arrayAll(rt object.Runtime, args ...object.Object) object.Object {
+	return arrayTest(rt, "all", false, args)
+}
+
+// arrayTest calls fn on the elements until it returns stop, meaning yea for
+// any and nah for all, and reports whether it did.
+func arrayTest(rt object.Runtime, name string, stop bool, args []object.Object) object.Object {
+	arr, fn, err := arrayAndFunction(name, args)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		result := rt.Call(fn, el)
+		if isError(result) {
+			return result
+		}
+		if isTruthy(result) == stop {
+			return nativeBoolToBooleanObject(stop)
+		}
+	}
+	return nativeBoolToBooleanObject(!stop)
+}
+
+// arraySort sorts a copy of the array in ascending order, or with the
+// comparator given as second argument, which reports whether its first
+// argument goes before its second. By default numbers are compared with `<`
+// and strings byte by byte. The sort is stable.
+func arraySort(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	arr, err := arrayArg("sort", args[0])
+	if err != nil {
+		return err
+	}
+
+	less := func(a, b object.Object) object.Object {
+		if a, ok := a.(*object.String); ok {
+			if b, ok := b.(*object.String); ok {
+				return nativeBoolToBooleanObject(a.Value < b.Value)
+			}
+		}
+		return evalInfixExpression("<", a, b)
+	}
+	if len(args) == 2 {
+		fn, err := functionArg("sort", args[1])
+		if err != nil {
+			return err
+		}
+		less = func(a, b object.Object) object.Object { return rt.Call(fn, a, b) }
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	var failed object.Object
+	sort.SliceStable(elements, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+		result := less(elements[i], elements[j])
+		if isError(result) {
+			failed = result
+			return false
+		}
+		return isTruthy(result)
+	})
+	if failed != nil {
+		return failed
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayReverse(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("reverse", args)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		elements[len(elements)-1-i] = el
+	}
+	return &object.Array{Elements: elements}
+}
+
+func arrayFirst(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("first", args)
+	if err != nil {
+		return err
+	}
+
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+	return arr.Elements[0]
+}
+
+func arrayLast(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("last", args)
+	if err != nil {
+		return err
+	}
+
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+	return arr.Elements[len(arr.Elements)-1]
+}
+
+// arrayRest returns all elements but the first, or null for an empty array.
+func arrayRest(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("rest", args)
+	if err != nil {
+		return err
+	}
+
+	if len(arr.Elements) == 0 {
+		return NULL
+	}
+	elements := make([]object.Object, len(arr.Elements)-1)
+	copy(elements, arr.Elements[1:])
+	return &object.Array{Elements: elements}
+}
+
+// arraySlice is slice(arr, start, end), the same as arr[start:end]; without
+// an end it slices to the end of the array.
+func arraySlice(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	if _, err := arrayArg("slice", args[0]); err != nil {
+		return err
+	}
+
+	end := object.Object(NULL)
+	if len(args) == 3 {
+		end = args[2]
+	}
+	return evalSliceExpression(args[0], args[1], end)
+}
+
+func arrayConcat(rt object.Runtime, args ...object.Object) object.Object {
+	elements := []object.Object{}
+	for _, arg := range args {
+		arr, err := arrayArg("concat", arg)
+		if err != nil {
+			return err
+		}
+		elements = append(elements, arr.Elements...)
+	}
+	return &object.Array{Elements: elements}
+}
+
+// arrayZip pairs up the elements of two arrays, up to the end of the shorter
+// one.
+func arrayZip(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	left, err := arrayArg("zip", args[0])
+	if err != nil {
+		return err
+	}
+	right, err := arrayArg("zip", args[1])
+	if err != nil {
+		return err
+	}
+
+	pairs := make([]object.Object, min(len(left.Elements), len(right.Elements)))
+	for i := range pairs {
+		pairs[i] = &object.Array{Elements: []object.Object{left.Elements[i], right.Elements[i]}}
+	}
+	return &object.Array{Elements: pairs}
+}
+
+// arrayFlatten replaces the arrays among the elements with their own
+// elements. It flattens one level.
+func arrayFlatten(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("flatten", args)
+	if err != nil {
+		return err
+	}
+
+	elements := []object.Object{}
+	for _, el := range arr.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			elements = append(elements, nested.Elements...)
+		} else {
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// arrayUnique drops the elements equal to an earlier one. Elements are
+// compared as hash keys, so they must be usable as one.
+func arrayUnique(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("unique", args)
+	if err != nil {
+		return err
+	}
+
+	seen := object.NewHash(len(arr.Elements))
+	elements := []object.Object{}
+	for _, el := range arr.Elements {
+		key, ok := object.AsKey(el)
+		if !ok {
+			return newError("unusable as hash key: %s", el.Type())
+		}
+		if _, ok := seen.Get(key); !ok {
+			seen.Set(key, TRUE)
+			elements = append(elements, el)
+		}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// oneArray checks the arguments of a builtin that takes a single array.
+func oneArray(name string, args []object.Object) (*object.Array, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return arrayArg(name, args[0])
+}
+
+// arrayAndFunction checks the arguments of a builtin that takes an array and
+// a function to call on its elements.
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, err := arrayArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := functionArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return arr, fn, nil
+}
+
+func arrayArg(name string, arg object.Object) (*object.Array, *object.Error) {
+	arr, ok := arg.(*object.Array)
+	if !ok {
+		return nil, newError("argument to `%s` must be ARRAY, got %s", name, arg.Type())
+	}
+	return arr, nil
+}
+
+func functionArg(name string, arg object.Object) (object.Object, *object.Error) {
+	switch arg.(type) {
+	case *object.Function, *object.Closure, *object.Builtin:
+		return arg, nil
+	}
+	return nil, newError("argument to `%s` must be FUNCTION, got %s", name, arg.Type())
+}
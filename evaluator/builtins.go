@@ -2,37 +2,50 @@ package evaluator
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/amirhesham65/zzz-lang/object"
 )
 
+// NewBuiltins returns a fresh copy of the default builtins, which callers may extend
+// or trim without affecting other evaluations.
+func NewBuiltins() map[string]*object.Builtin {
+	fresh := make(map[string]*object.Builtin, len(builtins))
+	for name, builtin := range builtins {
+		fresh[name] = builtin
+	}
+	return fresh
+}
+
 var builtins = map[string]*object.Builtin{
 	"spit": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				fmt.Fprintln(rt.Stdout(), arg.Inspect())
 			}
 			return nil
 		},
 	},
 	"len": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(arg.Len())}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"push": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2", len(args))
 			}
@@ -49,4 +62,77 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	"freeze": {
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `freeze` must be ARRAY, got %s", args[0].Type())
+			}
+			return freeze(arr)
+		},
+	},
+	"map":     {Fn: arrayMap},
+	"filter":  {Fn: arrayFilter},
+	"reduce":  {Fn: arrayReduce},
+	"each":    {Fn: arrayEach},
+	"find":    {Fn: arrayFind},
+	"any":     {Fn: arrayAny},
+	"all":     {Fn: arrayAll},
+	"sort":    {Fn: arraySort},
+	"reverse": {Fn: arrayReverse},
+	"first":   {Fn: arrayFirst},
+	"last":    {Fn: arrayLast},
+	"rest":    {Fn: arrayRest},
+	"slice":   {Fn: arraySlice},
+	"concat":  {Fn: arrayConcat},
+	"zip":     {Fn: arrayZip},
+	"flatten": {Fn: arrayFlatten},
+	"unique":  {Fn: arrayUnique},
+	"keys":    {Fn: hashKeys},
+	"values":  {Fn: hashValues},
+	"entries": {Fn: hashEntries},
+	"has":     {Fn: hashHas},
+	"get":     {Fn: hashGet},
+	"delete":  {Fn: hashDelete},
+	"merge":   {Fn: hashMerge},
+
+	"type":       {Fn: typeOf},
+	"str":        {Fn: convertStr},
+	"int":        {Fn: convertInt},
+	"float":      {Fn: convertFloat},
+	"bool":       {Fn: convertBool},
+	"repr":       {Fn: reprOf},
+	"isFunction": {Fn: isFunction},
+	"callable":   {Fn: isFunction},
+
+	"format": {Fn: formatBuiltin},
+	"print":  {Fn: printBuiltin},
+	"printf": {Fn: printfBuiltin},
+}
+
+// freeze returns a frozen copy of arr, with its nested arrays frozen as well,
+// or an error if an element could not be a hash key.
+func freeze(arr *object.Array) object.Object {
+	if arr.Frozen {
+		return arr
+	}
+
+	elements := make([]object.Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		if nested, ok := el.(*object.Array); ok {
+			el = freeze(nested)
+			if isError(el) {
+				return el
+			}
+		}
+		if _, ok := object.AsKey(el); !ok {
+			return newError("cannot freeze an array containing %s", el.Type())
+		}
+		elements[i] = el
+	}
+	return &object.Array{Elements: elements, Frozen: true}
 }
@@ -0,0 +1,158 @@
+package evaluator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// typeNames maps object types to the names `type` returns for them, which are
+// the names type annotations use.
+var typeNames = map[object.ObjectType]string{
+	object.INTEGER_OBJ:  "int",
+	object.FLOAT_OBJ:    "float",
+	object.STRING_OBJ:   "string",
+	object.BOOLEAN_OBJ:  "bool",
+	object.NULL_OBJ:     "null",
+	object.ARRAY_OBJ:    "array",
+	object.HASH_OBJ:     "hash",
+	object.FUNCTION_OBJ: "fun",
+	object.BUILTIN_OBJ:  "fun",
+	object.MODULE_OBJ:   "module",
+}
+
+func typeOf(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	name, ok := typeNames[args[0].Type()]
+	if !ok {
+		name = strings.ToLower(string(args[0].Type()))
+	}
+	return &object.String{Value: name}
+}
+
+func convertStr(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	if str, ok := args[0].(*object.String); ok {
+		return str
+	}
+	return &object.String{Value: args[0].Inspect()}
+}
+
+// convertInt converts numbers, booleans and decimal strings to integers.
+// Floats are truncated toward zero.
+func convertInt(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+			return conversionError(arg, object.INTEGER_OBJ)
+		}
+		return &object.Integer{Value: int64(arg.Value)}
+	case *object.Boolean:
+		if arg.Value {
+			return &object.Integer{Value: 1}
+		}
+		return &object.Integer{Value: 0}
+	case *object.String:
+		value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+		if err != nil {
+			return conversionError(arg, object.INTEGER_OBJ)
+		}
+		return &object.Integer{Value: value}
+	default:
+		return newError("argument to `int` not supported, got %s", args[0].Type())
+	}
+}
+
+func convertFloat(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Float:
+		return arg
+	case *object.Integer:
+		return &object.Float{Value: float64(arg.Value)}
+	case *object.Boolean:
+		if arg.Value {
+			return &object.Float{Value: 1}
+		}
+		return &object.Float{Value: 0}
+	case *object.String:
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil {
+			return conversionError(arg, object.FLOAT_OBJ)
+		}
+		return &object.Float{Value: value}
+	default:
+		return newError("argument to `float` not supported, got %s", args[0].Type())
+	}
+}
+
+// convertBool reports whether a value counts as true in a condition.
+func convertBool(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return nativeBoolToBooleanObject(isTruthy(args[0]))
+}
+
+// reprOf is like str, but quotes strings, including those inside arrays and
+// hashes, so that "1" and 1 can be told apart.
+func reprOf(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return &object.String{Value: repr(args[0])}
+}
+
+func repr(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return strconv.Quote(obj.Value)
+	case *object.Array:
+		elements := make([]string, len(obj.Elements))
+		for i, el := range obj.Elements {
+			elements[i] = repr(el)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *object.Hash:
+		pairs := make([]string, obj.Len())
+		for i, pair := range obj.Pairs() {
+			pairs[i] = repr(pair.Key) + ": " + repr(pair.Value)
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	default:
+		return obj.Inspect()
+	}
+}
+
+func isFunction(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	_, err := functionArg("isFunction", args[0])
+	return nativeBoolToBooleanObject(err == nil)
+}
+
+// conversionError reports a value that cannot be converted to typ.
+func conversionError(value object.Object, typ object.ObjectType) *object.Error {
+	err := newError("cannot convert %s to %s", repr(value), typ)
+	err.Kind = object.CONVERSION_ERR
+	return err
+}
@@ -0,0 +1,129 @@
+package evaluator_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/optimizer"
+	"github.com/amirhesham65/zzz-lang/parser"
+	"github.com/amirhesham65/zzz-lang/vm"
+)
+
+type engineFunc func(ctx context.Context, program *ast.Program, globals map[string]object.Object, opts evaluator.Options) object.Object
+
+// engines lists every way a program can be executed. The tests in this package
+// run each input on all of them and require the results to agree.
+var engines = []struct {
+	name string
+	run  engineFunc
+}{
+	{"evaluator", runEvaluator},
+	{"vm", runVM},
+	{"evaluator (optimized)", optimized(runEvaluator)},
+	{"vm (optimized)", optimized(runVM)},
+}
+
+func runEvaluator(ctx context.Context, program *ast.Program, globals map[string]object.Object, opts evaluator.Options) object.Object {
+	env := object.NewEnvironment()
+	for name, val := range globals {
+		env.Set(name, val)
+	}
+	return evaluator.EvalContext(ctx, program, env, opts)
+}
+
+func runVM(ctx context.Context, program *ast.Program, globals map[string]object.Object, opts evaluator.Options) object.Object {
+	env := vm.NewEnv()
+	for name, val := range globals {
+		env.Set(name, val)
+	}
+	return vm.EvalContext(ctx, program, env, opts)
+}
+
+// optimized runs the program through the optimizer before handing it to run.
+func optimized(run engineFunc) engineFunc {
+	return func(ctx context.Context, program *ast.Program, globals map[string]object.Object, opts evaluator.Options) object.Object {
+		program = optimizer.Optimize(program)
+		opts.Literals = optimizer.Literals(program)
+		return run(ctx, program, globals, opts)
+	}
+}
+
+// testEngines runs input on every engine with globals predefined, reports any
+// engine whose result differs from the evaluator's, and returns the latter.
+func testEngines(t *testing.T, ctx context.Context, input string, opts evaluator.Options, globals map[string]object.Object) object.Object {
+	t.Helper()
+
+	var expected object.Object
+	for i, engine := range engines {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		got := engine.run(ctx, program, globals, opts)
+
+		if i == 0 {
+			expected = got
+			continue
+		}
+		if !sameResult(expected, got) {
+			t.Errorf("%s disagrees with %s on %q.\n%s=%s\n%s=%s", engine.name, engines[0].name, input,
+				engines[0].name, describe(expected), engine.name, describe(got))
+		}
+	}
+	return expected
+}
+
+// sameResult compares results across engines. Builtins and modules only need
+// to agree on their type, functions also on how they print.
+func sameResult(a, b object.Object) bool {
+	if a == nil {
+		a = object.NULL
+	}
+	if b == nil {
+		b = object.NULL
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Error:
+		b := b.(*object.Error)
+		return a.Message == b.Message && a.Kind == b.Kind
+	case *object.Array:
+		b := b.(*object.Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !sameResult(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		b := b.(*object.Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i, pair := range a.Pairs() {
+			other := b.Pairs()[i]
+			if !sameResult(pair.Key, other.Key) || !sameResult(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	case *object.Builtin, *object.Module:
+		return true
+	}
+
+	return a.Inspect() == b.Inspect()
+}
+
+func describe(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	return string(obj.Type()) + " " + obj.Inspect()
+}
@@ -1,8 +1,13 @@
-package evaluator
+package evaluator_test
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
 	"testing"
 
+	"github.com/amirhesham65/zzz-lang/evaluator"
 	"github.com/amirhesham65/zzz-lang/lexer"
 	"github.com/amirhesham65/zzz-lang/object"
 	"github.com/amirhesham65/zzz-lang/parser"
@@ -28,10 +33,16 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"3 * 2 ** 2", 12},
+		{"7 ** 0", 1},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
@@ -66,10 +77,15 @@ func TestEvalBooleanExpressions(t *testing.T) {
 		{"(1 < 2) == nah", false},
 		{"(1 > 2) == yea", false},
 		{"(1 < 2) == nah", false},
+		{`"zzz" == "zzz"`, true},
+		{`"zzz" != "zzz"`, false},
+		{`"zzz" == "z" + "zz"`, true},
+		{`lit s = fun() { "a" }; s() == s()`, true},
+		{`"a" != "b"`, true},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
@@ -89,7 +105,7 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -148,7 +164,7 @@ func TestReturnStatements(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		testIntegerObject(t, evaluated, tt.expected)
 	}
 }
@@ -160,7 +176,76 @@ func TestClosures(t *testing.T) {
 };
    lit addTwo = newAdder(2);
    addTwo(2);`
-	testIntegerObject(t, testEval(input), 4)
+	testIntegerObject(t, testEval(t, input), 4)
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"lit x = 1; lit f = fun(x) { x * 10 }; f(2) + x", 21},
+		{"lit x = 1; lit f = fun() { lit x = x + 1; x }; f() + x", 3},
+		{"lit f = fun(a) { fun(b) { fun(c) { a + b + c } } }; f(1)(2)(3)", 6},
+		{"lit f = fun(n) { fr (n > 0) { lit m = n * 2; m } lowkey { 0 } }; f(4)", 8},
+		{"lit counter = fun() { lit n = 0; fun() { lit n = n + 1; n } }; lit c = counter(); c(); c()", 1},
+		{"lit f = fun(a, a) { a }; f(1, 2)", 2},
+		{"lit g = 5; lit f = fun() { g }; lit g = 6; f()", 6},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+// A local slot is reserved for the whole function, but reading it before its
+// `lit` runs falls back to the binding the name had outside.
+func TestUnsetSlotsFallBackToOuterBindings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lit x = 1; lit f = fun(c) { fr (c) { lit x = 2; }; x }; f(nah) * 10 + f(yea)", "12"},
+		{"lit f = fun() { lit g = fun() { h() }; lit h = fun() { 7 }; g() }; f()", "7"},
+		{"lit f = fun() { lit g = fun() { h() }; lit h = fun() { 5 }; g() }; f()", "5"},
+		{"lit f = fun() { lit get = fun() { n }; lit n = 3; get() }; f()", "3"},
+		{"lit c = nah; lit f = fun() { fr (c) { lit x = 1 }; x }; f()", "undefined identifier: x"},
+		{"lit f = fun(c) { fr (c) { lit len = fun(a) { 0 } }; len([1, 2, 3]) }; [f(nah), f(yea)]", "[3, 0]"},
+		{"lit a = spit(1); a", "null"},
+		{"lit f = fun() { lit a = spit(1); a }; f()", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEngines(t, context.Background(), tt.input, evaluator.Options{Stdout: io.Discard}, nil)
+		testInspect(t, tt.input, evaluated, tt.expected)
+	}
+}
+
+func TestConcurrentEvaluation(t *testing.T) {
+	program := parser.New(lexer.New("lit f = fun(n) { lit m = n * 2; fun() { m + n } }; f(3)()")).ParseProgram()
+
+	results := make([]object.Object, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = evaluator.Eval(program, object.NewEnvironment())
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		testIntegerObject(t, result, 9)
+	}
+}
+
+func TestTypeAnnotationsDoNotAffectEvaluation(t *testing.T) {
+	input := `
+lit add = fun(a: int, b: int) -> int { a + b };
+lit x: int = add(1, 2);
+lit y: string = x;
+y * 2`
+	testIntegerObject(t, testEval(t, input), 6)
 }
 
 func TestLetStatements(t *testing.T) {
@@ -174,7 +259,7 @@ func TestLetStatements(t *testing.T) {
 		{"lit a = 5; lit b = a; lit c = a + b + 5; c;", 15},
 	}
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
@@ -225,6 +310,22 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			"10 / 0",
+			"division by zero",
+		},
+		{
+			"lit zero = fun() { 0 }; 1 / zero()",
+			"division by zero",
+		},
+		{
+			"2 ** -1",
+			"negative exponent: 2 ** -1",
+		},
+		{
+			`"a" ** 2`,
+			"type mismatch: STRING ** INTEGER",
+		},
 		{
 			`{"name": "Monkey"}[fun(x) { x }];`,
 			"unusable as hash key: FUNCTION",
@@ -232,7 +333,7 @@ func TestErrorHandling(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 
 		errObj, ok := evaluated.(*object.Error)
 		if !ok {
@@ -254,13 +355,14 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo, 世界")`, 9},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`spit("hello", "world")`, nil},
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
@@ -277,13 +379,9 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
-func testEval(input string) object.Object {
-	l := lexer.New(input)
-	p := parser.New(l)
-	program := p.ParseProgram()
-	env := object.NewEnvironment()
-
-	return Eval(program, env)
+func testEval(t *testing.T, input string) object.Object {
+	t.Helper()
+	return testEngines(t, context.Background(), input, evaluator.Options{}, nil)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -302,7 +400,7 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 func TestFunctionObject(t *testing.T) {
 	input := "fun(x) { x + 2; };"
 
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	fn, ok := evaluated.(*object.Function)
 	if !ok {
 		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
@@ -338,13 +436,13 @@ func TestFunctionApplication(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		testIntegerObject(t, testEval(tt.input), tt.expected)
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
 	}
 }
 
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!";`
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	str, ok := evaluated.(*object.String)
 	if !ok {
 		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
@@ -356,7 +454,7 @@ func TestStringLiteral(t *testing.T) {
 
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!";`
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	str, ok := evaluated.(*object.String)
 	if !ok {
 		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
@@ -368,7 +466,7 @@ func TestStringConcatenation(t *testing.T) {
 
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3];"
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	result, ok := evaluated.(*object.Array)
 
 	if !ok {
@@ -400,7 +498,7 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		integer, ok := tt.expected.(int)
 		if ok {
 			testIntegerObject(t, evaluated, int64(integer))
@@ -422,17 +520,28 @@ func TestHashLiterals(t *testing.T) {
            nah: 6
 		}
 	`
-	evaluated := testEval(input)
+	evaluated := testEval(t, input)
 	result, ok := evaluated.(*object.Hash)
 	if !ok {
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
-	expected := map[object.HashKey]int64{(&object.String{Value: "one"}).HashKey(): 1, (&object.String{Value: "two"}).HashKey(): 2, (&object.String{Value: "three"}).HashKey(): 3, (&object.Integer{Value: 4}).HashKey(): 4, TRUE.HashKey(): 5, FALSE.HashKey(): 6}
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	expected := []struct {
+		key   object.Hashable
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{object.TRUE, 5},
+		{object.FALSE, 6},
+	}
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
 	}
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for _, e := range expected {
+		expectedValue := e.value
+		pair, ok := result.Get(e.key)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
@@ -440,6 +549,75 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestHashLiteralOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"c": 1, "a": 2, "b": 3}`, "{c: 1, a: 2, b: 3}"},
+		{`{3: "x", 1: "y", yea: "z", "0": 0}`, `{3: x, 1: y, yea: z, 0: 0}`},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEngines(t, context.Background(), tt.input, evaluator.Options{}, nil)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong hash for %q. got=%q, expected=%q", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestHashLiteralEvaluatesLeftToRight(t *testing.T) {
+	input := `lit log = fun(x) { spit(x); x };
+{log("k1"): log(1), log("k2"): log(2), log("k3"): log(3)};`
+
+	for _, engine := range engines {
+		var out bytes.Buffer
+		program := parser.New(lexer.New(input)).ParseProgram()
+		engine.run(context.Background(), program, nil, evaluator.Options{Stdout: &out})
+
+		if out.String() != "k1\n1\nk2\n2\nk3\n3\n" {
+			t.Errorf("%s: wrong evaluation order. got=%q", engine.name, out.String())
+		}
+	}
+}
+
+func TestFrozenArrayKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lit h = {freeze([1, 2]): "a", freeze([2, 1]): "b"}; h[freeze([1, 2])]`, "a"},
+		{`lit h = {freeze([1, 2]): "a", freeze([2, 1]): "b"}; h[[2, 1].freeze()]`, "b"},
+		{`{freeze([1, [2, "x"]]): 1}[freeze([1, [2, "x"]])]`, "1"},
+		{`{freeze([1, 2]): 1}[freeze([1, 2, 3])]`, "null"},
+		{`{freeze([]): 1, freeze([1]): 2}`, "{[]: 1, [1]: 2}"},
+		{`{freeze([1]): 1, freeze([1]): 2}`, "{[1]: 2}"},
+		{`{"1": "string", 1: "integer", freeze([1]): "array"}[1]`, "integer"},
+		{`lit f = freeze([1]); f == freeze(f)`, "yea"},
+		{`{[1, 2]: 1}`, "ERROR: unusable as hash key: ARRAY"},
+		{`{"a": 1}[[1]]`, "ERROR: unusable as hash key: ARRAY"},
+		{`freeze([{}])`, "ERROR: cannot freeze an array containing HASH"},
+		{`freeze([[fun(x) { x }]])`, "ERROR: cannot freeze an array containing FUNCTION"},
+		{`freeze(1)`, "ERROR: argument to `freeze` must be ARRAY, got INTEGER"},
+		{`len(push(freeze([1]), 2))`, "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEngines(t, context.Background(), tt.input, evaluator.Options{}, nil)
+		got := "null"
+		if evaluated != nil {
+			got = evaluated.Inspect()
+			if err, ok := evaluated.(*object.Error); ok {
+				got = "ERROR: " + err.Message
+			}
+		}
+		if got != tt.expected {
+			t.Errorf("wrong result for %q. got=%q, expected=%q", tt.input, got, tt.expected)
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -455,7 +633,7 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		evaluated := testEval(tt.input)
+		evaluated := testEval(t, tt.input)
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
@@ -479,9 +657,450 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 }
 
 func testNullObject(t *testing.T, obj object.Object) bool {
-	if obj != NULL {
+	if obj != object.NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
 		return false
 	}
 	return true
 }
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"half * 2", "1.0"},
+		{"half + 1", "1.5"},
+		{"-half", "-0.5"},
+		{"1 / half", "2.0"},
+		{"half < 1", "yea"},
+		{"4 ** half", "2.0"},
+		{"half ** 2", "0.25"},
+		{"2 ** -half * 2 ** half", "1.0"},
+		{"half == half", "yea"},
+		{"half == 1", "nah"},
+		{"!half", "nah"},
+	}
+
+	for _, tt := range tests {
+		globals := map[string]object.Object{"half": &object.Float{Value: 0.5}}
+		evaluated := testEngines(t, context.Background(), tt.input, evaluator.Options{}, globals)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. got=%q, expected=%q", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestBuiltinOutput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`spit("hello", "world")`, "hello\nworld\n"},
+		{`spit(1 + 2, [1, "two"])`, "3\n[1, two]\n"},
+		{`lit f = fun(x) { spit(x) }; f(yea); f(nah);`, "yea\nnah\n"},
+		{`len("quiet")`, ""},
+	}
+
+	for _, tt := range tests {
+		for _, engine := range engines {
+			var out bytes.Buffer
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			engine.run(context.Background(), program, nil, evaluator.Options{Stdout: &out})
+
+			if out.String() != tt.expected {
+				t.Errorf("%s: wrong output for %q. got=%q, expected=%q", engine.name, tt.input, out.String(), tt.expected)
+			}
+		}
+	}
+}
+
+func TestPrintBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`print("a", 1, [2]); print("b")`, "a 1 [2]b"},
+		{`print()`, ""},
+		{`printf("%s has %d items", "cart", 3); spit("")`, "cart has 3 items\n"},
+		{`printf("%d%%|", 50); printf("%-3s|", "x")`, "50%|x  |"},
+		{`spit(fun(x) { x })`, "fun(x) {\nx\n}\n"},
+	}
+
+	for _, tt := range tests {
+		for _, engine := range engines {
+			var out bytes.Buffer
+			program := parser.New(lexer.New(tt.input)).ParseProgram()
+			engine.run(context.Background(), program, nil, evaluator.Options{Stdout: &out})
+
+			if out.String() != tt.expected {
+				t.Errorf("%s: wrong output for %q. got=%q, expected=%q", engine.name, tt.input, out.String(), tt.expected)
+			}
+		}
+	}
+
+	// Their results are values like any other.
+	input := `lit p = print(); [p, printf(""), type(p)]`
+	evaluated := testEngines(t, context.Background(), input, evaluator.Options{Stdout: io.Discard}, nil)
+	testInspect(t, input, evaluated, `[null, null, null]`)
+}
+
+func TestFunctionInspect(t *testing.T) {
+	input := `lit add = fun(a, b) { a + b }; [add, fun() { add(1, 2) }]`
+	evaluated := testEval(t, input)
+	testInspect(t, input, evaluated, "[fun(a, b) {\n(a + b)\n}, fun() {\nadd(1, 2)\n}]")
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`lit person = {"name": "Amir", "age": 25}; person.age`, 25},
+		{`{"inner": {"value": 7}}.inner.value`, 7},
+		{`{"double": fun(x) { x * 2 }}.double(4)`, 8},
+		{`{"name": "Amir"}.missing`, nil},
+		{`{"len": 3}.len`, 3},
+		{`"abc".upper()`, "ABC"},
+		{`"MiXeD".lower()`, "mixed"},
+		{`"four".len()`, 4},
+		{`[1, 2].push(3).len()`, 3},
+		{`lit up = "abc".upper; up()`, "ABC"},
+		{`5.abs`, "INTEGER has no member \"abs\""},
+		{`"abc".nope()`, "STRING has no member \"nope\""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			var got string
+			switch obj := evaluated.(type) {
+			case *object.String:
+				got = obj.Value
+			case *object.Error:
+				got = obj.Message
+			}
+			if got != expected {
+				t.Errorf("wrong result for %q. got=%q, expected=%q", tt.input, got, expected)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+// testInspect checks that evaluated prints as expected, or for an error that
+// its message is expected.
+func testInspect(t *testing.T, input string, evaluated object.Object, expected string) {
+	t.Helper()
+
+	var got string
+	switch obj := evaluated.(type) {
+	case nil:
+		got = "null"
+	case *object.Error:
+		got = obj.Message
+	default:
+		got = obj.Inspect()
+	}
+	if got != expected {
+		t.Errorf("wrong result for %q. got=%q, expected=%q", input, got, expected)
+	}
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a,b,,c".split(",")`, `[a, b, , c]`},
+		{`"héllo".split("")`, `[h, é, l, l, o]`},
+		{`["a", "b", "c"].join("-")`, `a-b-c`},
+		{`[1, "two", yea].join(", ")`, `1, two, yea`},
+		{`[].join(",")`, ``},
+		{`"  padded  ".trim()`, `padded`},
+		{`"héllo".upper()`, `HÉLLO`},
+		{`"ÀB".lower()`, `àb`},
+		{`"a-b-a".replace("a", "x")`, `x-b-x`},
+		{`"haystack".contains("st")`, `yea`},
+		{`"haystack".contains("needle")`, `nah`},
+		{`"zzz-lang".startsWith("zzz")`, `yea`},
+		{`"zzz-lang".endsWith("zzz")`, `nah`},
+		{`"héllo".indexOf("l")`, `2`},
+		{`"hello".indexOf("z")`, `-1`},
+		{`"ab".repeat(3)`, `ababab`},
+		{`"ab".repeat(0)`, ``},
+		{`"日本".chars()`, `[日, 本]`},
+		{`"héllo".len()`, `5`},
+		{`"ab".repeat(-1)`, "argument to `repeat` must not be negative, got -1"},
+		{`"ab".repeat("3")`, "argument to `repeat` must be INTEGER, got STRING"},
+		{`"ab".repeat(9223372036854775807)`, "`repeat` result is too long: 9223372036854775807 copies of 2 bytes"},
+		{`"a,b".split()`, "wrong number of arguments to `split`. got=0, want=1"},
+		{`"abc".upper(1)`, "wrong number of arguments to `upper`. got=1, want=0"},
+		{`[1].join(1)`, "argument to `join` must be STRING, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc"[0]`, `a`},
+		{`"héllo"[1]`, `é`},
+		{`lit s = "日本語"; s[len(s) - 1]`, `語`},
+		{`"abc"[3]`, `null`},
+		{`"abc"[-1]`, `null`},
+		{`"abc"["a"]`, "index operator not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello"[1:3]`, `el`},
+		{`"héllo"[1:3]`, `él`},
+		{`"hello"[:2]`, `he`},
+		{`"hello"[3:]`, `lo`},
+		{`"hello"[:]`, `hello`},
+		{`"hello"[-5:100]`, `hello`},
+		{`"hello"[4:1]`, ``},
+		{`[1, 2, 3, 4][1:3]`, `[2, 3]`},
+		{`lit a = [1, 2, 3]; lit b = a[:]; push(b, 4); a`, `[1, 2, 3]`},
+		{`lit a = [1, 2, 3]; a[len(a) - 1:]`, `[3]`},
+		{`[1, 2][5:]`, `[]`},
+		{`5[1:2]`, "slice operator not supported: INTEGER"},
+		{`"abc"["a":]`, "slice bound must be INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fun(x) { x * 2 })`, `[2, 4, 6]`},
+		{`[1, 2, 3].map(fun(x) { x * x })`, `[1, 4, 9]`},
+		{`map(["a", "bc"], len)`, `[1, 2]`},
+		{`filter([1, 2, 3, 4], fun(x) { x > 2 })`, `[3, 4]`},
+		{`reduce([1, 2, 3, 4], fun(acc, x) { acc + x })`, `10`},
+		{`reduce([], fun(acc, x) { acc + x }, 0)`, `0`},
+		{`reduce(["a", "b"], fun(acc, x) { acc + x }, ">")`, `>ab`},
+		{`each([1, 2], fun(x) { x })`, `null`},
+		{`find([1, 2, 3, 4], fun(x) { x > 1 })`, `2`},
+		{`find([1, 2], fun(x) { x > 5 })`, `null`},
+		{`any([1, 2, 3], fun(x) { x == 2 })`, `yea`},
+		{`any([], fun(x) { yea })`, `nah`},
+		{`all([1, 2, 3], fun(x) { x > 0 })`, `yea`},
+		{`all([1, 2, 3], fun(x) { x > 1 })`, `nah`},
+		{`sort([3, 1, 2])`, `[1, 2, 3]`},
+		{`sort(["b", "c", "a"])`, `[a, b, c]`},
+		{`sort([1, 3, 2], fun(a, b) { a > b })`, `[3, 2, 1]`},
+		{`sort([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], fun(a, b) { a[0] < b[0] })`, `[[1, b], [1, d], [2, a], [2, c]]`},
+		{`lit xs = [2, 1]; sort(xs); xs`, `[2, 1]`},
+		{`reverse([1, 2, 3])`, `[3, 2, 1]`},
+		{`first([1, 2])`, `1`},
+		{`first([])`, `null`},
+		{`last([1, 2])`, `2`},
+		{`rest([1, 2, 3])`, `[2, 3]`},
+		{`rest([])`, `null`},
+		{`slice([1, 2, 3, 4], 1, 3)`, `[2, 3]`},
+		{`slice([1, 2, 3, 4], 2)`, `[3, 4]`},
+		{`concat([1], [2, 3], [])`, `[1, 2, 3]`},
+		{`zip([1, 2, 3], ["a", "b"])`, `[[1, a], [2, b]]`},
+		{`flatten([1, [2, [3]], []])`, `[1, 2, [3]]`},
+		{`unique([1, 2, 1, "1", 2])`, `[1, 2, 1]`},
+		{`[3, 1, 2].sort().reverse().first()`, `3`},
+		{`map([1, 2], fun(x) { map([x], fun(y) { x * 10 + y }) })`, `[[11], [22]]`},
+		{`lit fact = fun(n) { fr (n < 2) { 1 } lowkey { n * fact(n - 1) } }; map([3, 5], fact)`, `[6, 120]`},
+		{`lit f = fun(x) { fr (x > 1) { return x * 100; } x }; map([1, 2], f)`, `[1, 200]`},
+		{`map([1, 2], fun(x) { x + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`map([1], fun(x) { x + "a" }); 5`, "type mismatch: INTEGER + STRING"},
+		{`map([1], fun() { 1 })`, "wrong number of arguments. got=1, want=0"},
+		{`map(1, len)`, "argument to `map` must be ARRAY, got INTEGER"},
+		{`map([1], 2)`, "argument to `map` must be FUNCTION, got INTEGER"},
+		{`filter([1])`, "wrong number of arguments. got=1, want=2"},
+		{`reduce([], fun(a, b) { a })`, "`reduce` of an empty ARRAY needs an initial value"},
+		{`sort([1, "a"])`, "type mismatch: STRING < INTEGER"},
+		{`sort([2, 1], fun(a, b) { a < "x" })`, "type mismatch: INTEGER < STRING"},
+		{`unique([[1]])`, "unusable as hash key: ARRAY"},
+		{`concat([1], 2)`, "argument to `concat` must be ARRAY, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestBuiltinCallbacksShareLimits(t *testing.T) {
+	input := `lit loop = fun(x) { loop(x) }; map([1], loop)`
+	for _, engine := range engines {
+		program := parser.New(lexer.New(input)).ParseProgram()
+		result := engine.run(context.Background(), program, nil, evaluator.Options{MaxDepth: 50})
+
+		err, ok := result.(*object.Error)
+		if !ok || err.Kind != object.DEPTH_LIMIT_ERR {
+			t.Errorf("%s: expected a depth limit error, got %s", engine.name, describe(result))
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"b": 1, "a": 2, 3: 3})`, `[b, a, 3]`},
+		{`values({"b": 1, "a": 2})`, `[1, 2]`},
+		{`entries({"b": 1, "a": 2})`, `[[b, 1], [a, 2]]`},
+		{`keys({})`, `[]`},
+		{`len({"a": 1, "b": 2})`, `2`},
+		{`{"a": 1}.len()`, `1`},
+		{`has({"a": first([])}, "a")`, `yea`},
+		{`has({"a": 1}, "b")`, `nah`},
+		{`has({[1].freeze(): 1}, [1].freeze())`, `yea`},
+		{`get({"a": 1}, "a", 0)`, `1`},
+		{`get({"a": 1}, "b", 0)`, `0`},
+		{`get({"a": 1}, "b")`, `null`},
+		{`get({"a": first([])}, "a", 0)`, `null`},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, `{a: 1, c: 3}`},
+		{`lit h = {"a": 1}; delete(h, "a"); h`, `{a: 1}`},
+		{`delete({"a": 1}, "z")`, `{a: 1}`},
+		{`merge({"a": 1, "b": 2}, {"c": 3, "a": 4})`, `{a: 4, b: 2, c: 3}`},
+		{`merge()`, `{}`},
+		{`lit h = {"a": 1}; merge(h, {"a": 2}); h`, `{a: 1}`},
+		{`lit h = {"b": 1, "a": 2}; h.keys().map(fun(k) { h[k] })`, `[1, 2]`},
+		{`{"keys": 1}.keys`, `1`},
+		{`keys([1])`, "argument to `keys` must be HASH, got ARRAY"},
+		{`has({}, [1])`, "unusable as hash key: ARRAY"},
+		{`get({})`, "wrong number of arguments. got=1, want=2 or 3"},
+		{`merge({}, 1)`, "argument to `merge` must be HASH, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`type(1)`, `int`},
+		{`type(float(1))`, `float`},
+		{`type("a")`, `string`},
+		{`type(yea)`, `bool`},
+		{`type(first([]))`, `null`},
+		{`type([])`, `array`},
+		{`type({})`, `hash`},
+		{`type(fun() { 1 })`, `fun`},
+		{`type(len)`, `fun`},
+		{`str(12) + "!"`, `12!`},
+		{`str([1, "a"])`, `[1, a]`},
+		{`str("a")`, `a`},
+		{`int("42") + 1`, `43`},
+		{`int(" -7 ")`, `-7`},
+		{`int(float("3.9"))`, `3`},
+		{`int(float("-3.9"))`, `-3`},
+		{`int(yea)`, `1`},
+		{`float(3)`, `3.0`},
+		{`float("2.5")`, `2.5`},
+		{`bool(0)`, `yea`},
+		{`bool(first([]))`, `nah`},
+		{`bool(nah)`, `nah`},
+		{`repr("a")`, `"a"`},
+		{`repr(["a", 1, {"k": "v"}])`, `["a", 1, {"k": "v"}]`},
+		{`repr(1)`, `1`},
+		{`isFunction(len)`, `yea`},
+		{`isFunction(fun(x) { x })`, `yea`},
+		{`callable("len")`, `nah`},
+		{`int("abc")`, `cannot convert "abc" to INTEGER`},
+		{`int("1.5")`, `cannot convert "1.5" to INTEGER`},
+		{`float("x")`, `cannot convert "x" to FLOAT`},
+		{`int(float("1e30"))`, `cannot convert 1e+30 to INTEGER`},
+		{`int([1])`, "argument to `int` not supported, got ARRAY"},
+		{`type()`, "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestConversionErrorKind(t *testing.T) {
+	err, ok := testEval(t, `int("abc")`).(*object.Error)
+	if !ok || err.Kind != object.CONVERSION_ERR {
+		t.Fatalf("expected a conversion error, got %+v", err)
+	}
+	if err.IsLimit() {
+		t.Errorf("a conversion error counts as a limit")
+	}
+}
+
+func TestTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lit name = "zzz"; "hi ${name}!"`, `hi zzz!`},
+		{`"${1 + 2} and ${[1, "a"]}"`, `3 and [1, a]`},
+		{`"${"nested ${"deep"}"}"`, `nested deep`},
+		{`"${ {"k": "v"}["k"] }"`, `v`},
+		{`"[${first([])}]"`, `[null]`},
+		{`lit f = fun(x) { "<${x}>" }; f(1) + f(yea)`, `<1><yea>`},
+		{`"${-yea}"`, `unknown operator: -BOOLEAN`},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestFormatBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("%d + %d = %d", 1, 2, 3)`, `1 + 2 = 3`},
+		{`format("%5d|%-5d|%05d", 42, 42, 42)`, `   42|42   |00042`},
+		{`format("%+d %x", 7, 255)`, `+7 ff`},
+		{`format("%.2f", float("3.14159"))`, `3.14`},
+		{`format("%8.3f|", 2)`, `   2.000|`},
+		{`format("%e %g", float("1234.5"), float("0.5"))`, `1.234500e+03 0.5`},
+		{`format("%s and %q", "a", "a")`, `a and "a"`},
+		{`format("%s %q", [1, "b"], [1, "b"])`, `[1, b] [1, "b"]`},
+		{`format("%-4s|%4s", "ab", "cd")`, `ab  |  cd`},
+		{`format("100%%")`, `100%`},
+		{`format("%d", "1")`, "`format`: %d needs INTEGER, got STRING"},
+		{`format("%f", "1")`, "`format`: %f needs a number, got STRING"},
+		{`format("%d %d", 1)`, "`format`: missing value for %d"},
+		{`format("%d", 1, 2, 3)`, "`format`: 2 values left over"},
+		{`format("%y", 1)`, "`format`: unknown verb %y"},
+		{`format("50%")`, "`format`: incomplete verb % at end of format"},
+		{`format(1)`, "argument to `format` must be STRING, got INTEGER"},
+		{`format()`, "wrong number of arguments. got=0, want at least 1"},
+		{`printf("%d", "x")`, "`printf`: %d needs INTEGER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, tt.input, testEval(t, tt.input), tt.expected)
+	}
+}
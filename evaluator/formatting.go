@@ -0,0 +1,125 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+func formatBuiltin(rt object.Runtime, args ...object.Object) object.Object {
+	str, err := formatArgs("format", args)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: str}
+}
+
+// printfBuiltin writes what format would return for its arguments.
+func printfBuiltin(rt object.Runtime, args ...object.Object) object.Object {
+	str, err := formatArgs("printf", args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(rt.Stdout(), str)
+	return NULL
+}
+
+// printBuiltin writes its arguments separated by spaces, without the newline
+// spit ends each of them with.
+func printBuiltin(rt object.Runtime, args ...object.Object) object.Object {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.Inspect()
+	}
+	fmt.Fprint(rt.Stdout(), strings.Join(values, " "))
+	return NULL
+}
+
+// formatArgs checks the arguments of a builtin taking a format string and the
+// values for its verbs, and formats them.
+func formatArgs(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return "", newError("wrong number of arguments. got=0, want at least 1")
+	}
+	format, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return formatValues(name, format.Value, args[1:])
+}
+
+// formatValues replaces the verbs of format with values, in the manner of
+// Go's fmt package. A verb is `%`, optional flags among `-+ 0`, an optional
+// width and `.precision`, and one of:
+//
+//	%d  an integer, %x the same in hexadecimal
+//	%f  a number with a decimal point, %e in scientific notation, %g the shorter
+//	%s  any value as str prints it
+//	%q  any value as repr prints it
+//	%%  a literal percent sign
+func formatValues(name, format string, values []object.Object) (string, *object.Error) {
+	var out strings.Builder
+	next := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		start := i
+		for i++; i < len(format) && strings.IndexByte("-+ 0.123456789", format[i]) >= 0; i++ {
+		}
+		if i == len(format) {
+			return "", newError("`%s`: incomplete verb %s at end of format", name, format[start:])
+		}
+		spec, verb := format[start:i], format[i]
+		if verb == '%' && spec == "%" {
+			out.WriteByte('%')
+			continue
+		}
+		if strings.IndexByte("dxfegsq", verb) < 0 {
+			return "", newError("`%s`: unknown verb %s%c", name, spec, verb)
+		}
+
+		if next == len(values) {
+			return "", newError("`%s`: missing value for %s%c", name, spec, verb)
+		}
+		value := values[next]
+		next++
+
+		var arg any
+		switch verb {
+		case 'd', 'x':
+			integer, ok := value.(*object.Integer)
+			if !ok {
+				return "", newError("`%s`: %s%c needs INTEGER, got %s", name, spec, verb, value.Type())
+			}
+			arg = integer.Value
+		case 'f', 'e', 'g':
+			switch value := value.(type) {
+			case *object.Integer:
+				arg = float64(value.Value)
+			case *object.Float:
+				arg = value.Value
+			default:
+				return "", newError("`%s`: %s%c needs a number, got %s", name, spec, verb, value.Type())
+			}
+		case 's':
+			if str, ok := value.(*object.String); ok {
+				arg = str.Value
+			} else {
+				arg = value.Inspect()
+			}
+		case 'q':
+			arg, verb = repr(value), 's'
+		}
+		fmt.Fprintf(&out, spec+string(verb), arg)
+	}
+
+	if next < len(values) {
+		return "", newError("`%s`: %d values left over", name, len(values)-next)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,148 @@
+package evaluator
+
+import "github.com/amirhesham65/zzz-lang/object"
+
+// The builtins below work on hashes and list pairs in insertion order. Like
+// the array builtins, they never modify the hashes they are given.
+
+func hashKeys(rt object.Runtime, args ...object.Object) object.Object {
+	hash, err := oneHash("keys", args)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]object.Object, hash.Len())
+	for i, pair := range hash.Pairs() {
+		keys[i] = pair.Key
+	}
+	return &object.Array{Elements: keys}
+}
+
+func hashValues(rt object.Runtime, args ...object.Object) object.Object {
+	hash, err := oneHash("values", args)
+	if err != nil {
+		return err
+	}
+
+	values := make([]object.Object, hash.Len())
+	for i, pair := range hash.Pairs() {
+		values[i] = pair.Value
+	}
+	return &object.Array{Elements: values}
+}
+
+// hashEntries returns the pairs of a hash as [key, value] arrays.
+func hashEntries(rt object.Runtime, args ...object.Object) object.Object {
+	hash, err := oneHash("entries", args)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]object.Object, hash.Len())
+	for i, pair := range hash.Pairs() {
+		entries[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+	}
+	return &object.Array{Elements: entries}
+}
+
+// hashHas reports whether a key is present, even if its value is null.
+func hashHas(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	hash, key, err := hashAndKey("has", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	_, ok := hash.Get(key)
+	return nativeBoolToBooleanObject(ok)
+}
+
+// hashGet is get(hash, key, default): the value stored under key, or default
+// (null if left out) when the key is missing.
+func hashGet(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	hash, key, err := hashAndKey("get", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	if pair, ok := hash.Get(key); ok {
+		return pair.Value
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return NULL
+}
+
+// hashDelete returns a copy of the hash without key.
+func hashDelete(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	hash, key, err := hashAndKey("delete", args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	copied := copyHash(hash)
+	copied.Delete(key)
+	return copied
+}
+
+// hashMerge combines hashes into a new one. A key set by a later hash takes
+// its value from it but keeps the place it was first set in.
+func hashMerge(rt object.Runtime, args ...object.Object) object.Object {
+	merged := object.NewHash(0)
+	for _, arg := range args {
+		hash, err := hashArg("merge", arg)
+		if err != nil {
+			return err
+		}
+		for _, pair := range hash.Pairs() {
+			merged.Set(pair.Key.(object.Hashable), pair.Value)
+		}
+	}
+	return merged
+}
+
+func copyHash(hash *object.Hash) *object.Hash {
+	copied := object.NewHash(hash.Len())
+	for _, pair := range hash.Pairs() {
+		copied.Set(pair.Key.(object.Hashable), pair.Value)
+	}
+	return copied
+}
+
+// oneHash checks the arguments of a builtin that takes a single hash.
+func oneHash(name string, args []object.Object) (*object.Hash, *object.Error) {
+	if len(args) != 1 {
+		return nil, newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return hashArg(name, args[0])
+}
+
+// hashAndKey checks the arguments of a builtin that looks up a key in a hash.
+func hashAndKey(name string, hashObj, keyObj object.Object) (*object.Hash, object.Hashable, *object.Error) {
+	hash, err := hashArg(name, hashObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := object.AsKey(keyObj)
+	if !ok {
+		return nil, nil, newError("unusable as hash key: %s", keyObj.Type())
+	}
+	return hash, key, nil
+}
+
+func hashArg(name string, arg object.Object) (*object.Hash, *object.Error) {
+	hash, ok := arg.(*object.Hash)
+	if !ok {
+		return nil, newError("argument to `%s` must be HASH, got %s", name, arg.Type())
+	}
+	return hash, nil
+}
@@ -0,0 +1,103 @@
+package evaluator
+
+import "github.com/amirhesham65/zzz-lang/object"
+
+// Approximate sizes, in bytes, used to account allocations against Options.MaxMemory.
+const (
+	objectSize = 16
+	slotSize   = 16
+	envSize    = 48
+	pairSize   = 48
+)
+
+// ctxCheckInterval is how many steps pass between polls of the context.
+const ctxCheckInterval = 1024
+
+// step accounts for one evaluated node and reports whether evaluation must stop.
+func (e *evaluation) step() *object.Error {
+	if e.stopped != nil {
+		return e.stopped
+	}
+
+	e.steps++
+	if e.opts.MaxSteps > 0 && e.steps > e.opts.MaxSteps {
+		return e.stop(object.STEP_LIMIT_ERR, "step limit exceeded: %d", e.opts.MaxSteps)
+	}
+
+	if e.steps%ctxCheckInterval == 1 {
+		if err := e.ctx.Err(); err != nil {
+			return e.stop(object.CANCELED_ERR, "evaluation canceled: %s", err)
+		}
+	}
+	return nil
+}
+
+// enter accounts for a user function call.
+func (e *evaluation) enter() *object.Error {
+	e.depth++
+	if limit := e.opts.Depth(); e.depth > limit {
+		return e.stop(object.DEPTH_LIMIT_ERR, "call depth limit exceeded: %d", limit)
+	}
+	return nil
+}
+
+func (e *evaluation) leave() {
+	e.depth--
+}
+
+// charge accounts for n freshly allocated bytes.
+func (e *evaluation) charge(n int64) *object.Error {
+	if e.stopped != nil {
+		return e.stopped
+	}
+
+	e.memory += n
+	if e.opts.MaxMemory > 0 && e.memory > e.opts.MaxMemory {
+		return e.stop(object.MEMORY_LIMIT_ERR, "memory limit exceeded: %d bytes", e.opts.MaxMemory)
+	}
+	return nil
+}
+
+// Reserve implements object.Runtime.
+func (e *evaluation) Reserve(n int64) *object.Error {
+	if e.stopped != nil {
+		return e.stopped
+	}
+	if e.opts.MaxMemory > 0 && n > e.opts.MaxMemory-e.memory {
+		return e.stop(object.MEMORY_LIMIT_ERR, "memory limit exceeded: %d bytes", e.opts.MaxMemory)
+	}
+	return nil
+}
+
+// alloc charges the size of a freshly created object and passes it through.
+func (e *evaluation) alloc(obj object.Object) object.Object {
+	if err := e.charge(sizeOf(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+func (e *evaluation) stop(kind object.ErrorKind, format string, a ...any) *object.Error {
+	err := newError(format, a...)
+	err.Kind = kind
+	e.stopped = err
+	return err
+}
+
+// sizeOf approximates the bytes held directly by obj, not counting shared children.
+func sizeOf(obj object.Object) int64 {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.Float:
+		return objectSize
+	case *object.String:
+		return objectSize + int64(len(obj.Value))
+	case *object.Array:
+		return objectSize + int64(len(obj.Elements))*slotSize
+	case *object.Hash:
+		return objectSize + int64(obj.Len())*pairSize
+	case *object.Function:
+		return objectSize * 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,91 @@
+package evaluator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestEvalContextLimits(t *testing.T) {
+	runaway := "lit f = fun(x) { f(x + 1) }; f(0);"
+	// Each call does enough work that neither engine reaches the depth limit
+	// before the timeout.
+	slow := "lit spin = fun(n) { fr (n > 0) { spin(n - 1) } }; lit f = fun(x) { spin(100); f(x + 1) }; f(0);"
+
+	tests := []struct {
+		input        string
+		opts         evaluator.Options
+		expectedKind object.ErrorKind
+	}{
+		{runaway, evaluator.Options{MaxSteps: 1000}, object.STEP_LIMIT_ERR},
+		{runaway, evaluator.Options{MaxDepth: 100}, object.DEPTH_LIMIT_ERR},
+		// Without MaxDepth, DefaultMaxDepth stops the recursion before it
+		// overflows the Go stack.
+		{"lit f = fun(x) { 1 + f(x + 1) }; f(0);", evaluator.Options{Timeout: 2 * time.Second}, object.DEPTH_LIMIT_ERR},
+		{runaway, evaluator.Options{MaxMemory: 4096}, object.MEMORY_LIMIT_ERR},
+		{slow, evaluator.Options{MaxDepth: 100000, Timeout: 10 * time.Millisecond}, object.CANCELED_ERR},
+		{`lit grow = fun(s) { grow(s + s) }; grow("zzz");`, evaluator.Options{MaxMemory: 1 << 20}, object.MEMORY_LIMIT_ERR},
+		{`"zzz".repeat(1000000000000)`, evaluator.Options{MaxMemory: 1 << 20}, object.MEMORY_LIMIT_ERR},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalContext(t, context.Background(), tt.input, tt.opts)
+		testLimitError(t, evaluated, tt.expectedKind)
+	}
+}
+
+func TestEvalContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated := testEvalContext(t, ctx, "1 + 1", evaluator.Options{})
+	testLimitError(t, evaluated, object.CANCELED_ERR)
+}
+
+func TestEvalContextWithinLimits(t *testing.T) {
+	input := `
+	lit fib = fun(n) { fr (n < 2) { n } lowkey { fib(n - 1) + fib(n - 2) } };
+	fib(10);`
+
+	opts := evaluator.Options{MaxSteps: 100000, MaxMemory: 1 << 20, MaxDepth: 20, Timeout: time.Second}
+	testIntegerObject(t, testEvalContext(t, context.Background(), input, opts), 55)
+}
+
+func TestLimitErrorStopsEvaluation(t *testing.T) {
+	input := `
+	lit a = 1 + 1;
+	lit b = 2 + 2;
+	lit c = 3 + 3;`
+
+	env := object.NewEnvironment()
+	p := parser.New(lexer.New(input))
+	evaluated := evaluator.EvalContext(context.Background(), p.ParseProgram(), env, evaluator.Options{MaxSteps: 5})
+	testLimitError(t, evaluated, object.STEP_LIMIT_ERR)
+
+	if _, ok := env.Get("c"); ok {
+		t.Errorf("evaluation continued after the step limit was exceeded")
+	}
+}
+
+func testEvalContext(t *testing.T, ctx context.Context, input string, opts evaluator.Options) object.Object {
+	t.Helper()
+	return testEngines(t, ctx, input, opts, nil)
+}
+
+func testLimitError(t *testing.T, obj object.Object, kind object.ErrorKind) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if errObj.Kind != kind || !errObj.IsLimit() {
+		t.Errorf("error has wrong kind. got=%q (%s), expected=%q", errObj.Kind, errObj.Message, kind)
+		return false
+	}
+	return true
+}
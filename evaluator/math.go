@@ -0,0 +1,228 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// mathModule holds the members of std/math. Functions that take numbers
+// accept integers and floats alike.
+var mathModule = map[string]object.Object{
+	"pi":    &object.Float{Value: math.Pi},
+	"e":     &object.Float{Value: math.E},
+	"abs":   &object.Builtin{Fn: mathAbs},
+	"min":   &object.Builtin{Fn: extremum("min", "<")},
+	"max":   &object.Builtin{Fn: extremum("max", ">")},
+	"pow":   &object.Builtin{Fn: mathPow},
+	"floor": rounding("floor", math.Floor),
+	"ceil":  rounding("ceil", math.Ceil),
+	"round": rounding("round", math.Round),
+	"sqrt":  floatFunction("sqrt", math.Sqrt),
+	"sin":   floatFunction("sin", math.Sin),
+	"cos":   floatFunction("cos", math.Cos),
+	"tan":   floatFunction("tan", math.Tan),
+	"asin":  floatFunction("asin", math.Asin),
+	"acos":  floatFunction("acos", math.Acos),
+	"atan":  &object.Builtin{Fn: mathAtan},
+	"exp":   floatFunction("exp", math.Exp),
+	"log":   &object.Builtin{Fn: mathLog},
+	"gcd":   &object.Builtin{Fn: mathGcd},
+	"lcm":   &object.Builtin{Fn: mathLcm},
+}
+
+func mathAbs(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		if arg.Value < 0 {
+			return &object.Integer{Value: -arg.Value}
+		}
+		return arg
+	case *object.Float:
+		return &object.Float{Value: math.Abs(arg.Value)}
+	default:
+		return numberError("abs", arg)
+	}
+}
+
+// extremum builds min or max, which take either numbers or a single array of
+// them and return the one that compares best with operator.
+func extremum(name, operator string) object.BuiltinFunction {
+	return func(rt object.Runtime, args ...object.Object) object.Object {
+		values := args
+		if len(args) == 1 {
+			if arr, ok := args[0].(*object.Array); ok {
+				values = arr.Elements
+			}
+		}
+		if len(values) == 0 {
+			return newError("`%s` needs at least one number", name)
+		}
+
+		best := values[0]
+		for _, value := range values {
+			if !isNumber(value) {
+				return numberError(name, value)
+			}
+			if evalInfixExpression(operator, value, best) == TRUE {
+				best = value
+			}
+		}
+		return best
+	}
+}
+
+// mathPow is the `**` operator as a function.
+func mathPow(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return numberError("pow", arg)
+		}
+	}
+	return evalInfixExpression("**", args[0], args[1])
+}
+
+// rounding builds floor, ceil and round, which return integers.
+func rounding(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				return convertInt(rt, &object.Float{Value: fn(arg.Value)})
+			default:
+				return numberError(name, arg)
+			}
+		},
+	}
+}
+
+// floatFunction builds a function of one number that returns a float.
+func floatFunction(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if !isNumber(args[0]) {
+				return numberError(name, args[0])
+			}
+			return &object.Float{Value: fn(toFloat(args[0]))}
+		},
+	}
+}
+
+// mathAtan is atan(x), or atan(y, x) for the angle of the point (x, y), which
+// unlike atan(y / x) tells the quadrants apart.
+func mathAtan(rt object.Runtime, args ...object.Object) object.Object {
+	values, err := numberArgs("atan", args)
+	if err != nil {
+		return err
+	}
+	if len(values) == 2 {
+		return &object.Float{Value: math.Atan2(values[0], values[1])}
+	}
+	return &object.Float{Value: math.Atan(values[0])}
+}
+
+// mathLog is log(x) for the natural logarithm, or log(x, base).
+func mathLog(rt object.Runtime, args ...object.Object) object.Object {
+	values, err := numberArgs("log", args)
+	if err != nil {
+		return err
+	}
+	if len(values) == 1 {
+		return &object.Float{Value: math.Log(values[0])}
+	}
+
+	switch x, base := values[0], values[1]; base {
+	case 2:
+		return &object.Float{Value: math.Log2(x)}
+	case 10:
+		return &object.Float{Value: math.Log10(x)}
+	default:
+		return &object.Float{Value: math.Log(x) / math.Log(base)}
+	}
+}
+
+// numberArgs checks the arguments of a function taking one number and an
+// optional second one.
+func numberArgs(name string, args []object.Object) ([]float64, *object.Error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		if !isNumber(arg) {
+			return nil, numberError(name, arg)
+		}
+		values[i] = toFloat(arg)
+	}
+	return values, nil
+}
+
+// mathGcd returns the greatest common divisor of two integers, which is
+// never negative.
+func mathGcd(rt object.Runtime, args ...object.Object) object.Object {
+	a, b, err := twoIntegers("gcd", args)
+	if err != nil {
+		return err
+	}
+	return &object.Integer{Value: gcd(a, b)}
+}
+
+// mathLcm returns the least common multiple of two integers, or 0 if either
+// of them is 0.
+func mathLcm(rt object.Runtime, args ...object.Object) object.Object {
+	a, b, err := twoIntegers("lcm", args)
+	if err != nil {
+		return err
+	}
+	if a == 0 || b == 0 {
+		return &object.Integer{Value: 0}
+	}
+	lcm := a / gcd(a, b) * b
+	if lcm < 0 {
+		lcm = -lcm
+	}
+	return &object.Integer{Value: lcm}
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func twoIntegers(name string, args []object.Object) (int64, int64, *object.Error) {
+	if len(args) != 2 {
+		return 0, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	for _, arg := range args {
+		if arg.Type() != object.INTEGER_OBJ {
+			return 0, 0, newError("argument to `%s` must be INTEGER, got %s", name, arg.Type())
+		}
+	}
+	return args[0].(*object.Integer).Value, args[1].(*object.Integer).Value, nil
+}
+
+func numberError(name string, arg object.Object) *object.Error {
+	return newError("argument to `%s` must be a number, got %s", name, arg.Type())
+}
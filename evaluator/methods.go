@@ -0,0 +1,184 @@
+package evaluator
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// methods maps a receiver type to the builtins that can be called on it with
+// member syntax, as in `"abc".upper()`. A method receives its receiver as the
+// first argument, so `arr.push(x)` is the same call as `push(arr, x)`. A key
+// of a hash hides the method of the same name.
+var methods = map[object.ObjectType]map[string]*object.Builtin{
+	object.STRING_OBJ: {
+		"len": builtins["len"],
+		"upper": stringMethod("upper", nil, func(s string, args []object.Object) object.Object {
+			return &object.String{Value: strings.ToUpper(s)}
+		}),
+		"lower": stringMethod("lower", nil, func(s string, args []object.Object) object.Object {
+			return &object.String{Value: strings.ToLower(s)}
+		}),
+		"trim": stringMethod("trim", nil, func(s string, args []object.Object) object.Object {
+			return &object.String{Value: strings.TrimSpace(s)}
+		}),
+		"split": stringMethod("split", []object.ObjectType{object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			parts := strings.Split(s, args[0].(*object.String).Value)
+			elements := make([]object.Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &object.String{Value: part}
+			}
+			return &object.Array{Elements: elements}
+		}),
+		"chars": stringMethod("chars", nil, func(s string, args []object.Object) object.Object {
+			elements := make([]object.Object, 0, utf8.RuneCountInString(s))
+			for _, r := range s {
+				elements = append(elements, &object.String{Value: string(r)})
+			}
+			return &object.Array{Elements: elements}
+		}),
+		"replace": stringMethod("replace", []object.ObjectType{object.STRING_OBJ, object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			return &object.String{Value: strings.ReplaceAll(s, args[0].(*object.String).Value, args[1].(*object.String).Value)}
+		}),
+		"contains": stringMethod("contains", []object.ObjectType{object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			return nativeBoolToBooleanObject(strings.Contains(s, args[0].(*object.String).Value))
+		}),
+		"startsWith": stringMethod("startsWith", []object.ObjectType{object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			return nativeBoolToBooleanObject(strings.HasPrefix(s, args[0].(*object.String).Value))
+		}),
+		"endsWith": stringMethod("endsWith", []object.ObjectType{object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			return nativeBoolToBooleanObject(strings.HasSuffix(s, args[0].(*object.String).Value))
+		}),
+		"indexOf": stringMethod("indexOf", []object.ObjectType{object.STRING_OBJ}, func(s string, args []object.Object) object.Object {
+			i := strings.Index(s, args[0].(*object.String).Value)
+			if i < 0 {
+				return &object.Integer{Value: -1}
+			}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(s[:i]))}
+		}),
+		"repeat": {
+			Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+				if err := methodArgs("repeat", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				s, n := args[0].(*object.String).Value, args[1].(*object.Integer).Value
+				if n < 0 {
+					return newError("argument to `repeat` must not be negative, got %d", n)
+				}
+				if len(s) > 0 && n > math.MaxInt/int64(len(s)) {
+					return newError("`repeat` result is too long: %d copies of %d bytes", n, len(s))
+				}
+				if err := rt.Reserve(int64(len(s)) * n); err != nil {
+					return err
+				}
+				return &object.String{Value: strings.Repeat(s, int(n))}
+			},
+		},
+	},
+	object.ARRAY_OBJ: {
+		"len":     builtins["len"],
+		"push":    builtins["push"],
+		"freeze":  builtins["freeze"],
+		"map":     builtins["map"],
+		"filter":  builtins["filter"],
+		"reduce":  builtins["reduce"],
+		"each":    builtins["each"],
+		"find":    builtins["find"],
+		"any":     builtins["any"],
+		"all":     builtins["all"],
+		"sort":    builtins["sort"],
+		"reverse": builtins["reverse"],
+		"first":   builtins["first"],
+		"last":    builtins["last"],
+		"rest":    builtins["rest"],
+		"slice":   builtins["slice"],
+		"concat":  builtins["concat"],
+		"zip":     builtins["zip"],
+		"flatten": builtins["flatten"],
+		"unique":  builtins["unique"],
+		"join": {
+			Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+				if err := methodArgs("join", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				elements := args[0].(*object.Array).Elements
+				parts := make([]string, len(elements))
+				for i, el := range elements {
+					parts[i] = el.Inspect()
+				}
+				return &object.String{Value: strings.Join(parts, args[1].(*object.String).Value)}
+			},
+		},
+	},
+	object.HASH_OBJ: {
+		"len":     builtins["len"],
+		"keys":    builtins["keys"],
+		"values":  builtins["values"],
+		"entries": builtins["entries"],
+		"has":     builtins["has"],
+		"get":     builtins["get"],
+		"delete":  builtins["delete"],
+		"merge":   builtins["merge"],
+	},
+}
+
+// stringMethod builds a method of strings that takes arguments of the given
+// types after its receiver. fn is called once they have been checked.
+func stringMethod(name string, params []object.ObjectType, fn func(s string, args []object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+			if err := methodArgs(name, args, params...); err != nil {
+				return err
+			}
+			return fn(args[0].(*object.String).Value, args[1:])
+		},
+	}
+}
+
+// methodArgs checks the arguments a method was called with, after its
+// receiver args[0], against the parameter types it expects. Counts in the
+// error leave the receiver out, since it is not written between the parens.
+func methodArgs(name string, args []object.Object, params ...object.ObjectType) *object.Error {
+	if len(args)-1 != len(params) {
+		return newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args)-1, len(params))
+	}
+	for i, param := range params {
+		if args[i+1].Type() != param {
+			return newError("argument to `%s` must be %s, got %s", name, param, args[i+1].Type())
+		}
+	}
+	return nil
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.Module:
+		return evalModuleMember(obj, name)
+	case *object.Hash:
+		if pair, ok := obj.Get(&object.String{Value: name}); ok {
+			return pair.Value
+		}
+	}
+
+	if method, ok := methods[obj.Type()][name]; ok {
+		return bindMethod(method, obj)
+	}
+
+	if obj.Type() == object.HASH_OBJ {
+		return NULL
+	}
+	return newError("%s has no member %q", obj.Type(), name)
+}
+
+// bindMethod fixes receiver as the first argument of method.
+func bindMethod(method *object.Builtin, receiver object.Object) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(rt object.Runtime, args ...object.Object) object.Object {
+			return method.Fn(rt, append([]object.Object{receiver}, args...)...)
+		},
+	}
+}
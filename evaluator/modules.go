@@ -0,0 +1,179 @@
+package evaluator
+
+import (
+	"embed"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+// stdPrefix marks imports that are served from the embedded standard library.
+const stdPrefix = "std/"
+
+//go:embed std/*.zzz
+var stdlib embed.FS
+
+// nativeModules holds the members of the standard modules written in Go
+// rather than zzz, by their "std/..." name.
+var nativeModules = map[string]map[string]object.Object{
+	"std/math":   mathModule,
+	"std/random": randomModule,
+}
+
+// ModuleCache remembers evaluated modules so that each one is imported at most
+// once. Share a cache between evaluations to keep modules alive across them.
+type ModuleCache struct {
+	modules map[string]*object.Module
+}
+
+// NewModuleCache returns an empty ModuleCache.
+func NewModuleCache() *ModuleCache {
+	return &ModuleCache{modules: make(map[string]*object.Module)}
+}
+
+// Lookup returns the module cached under key.
+func (mc *ModuleCache) Lookup(key string) (*object.Module, bool) {
+	module, ok := mc.modules[key]
+	return module, ok
+}
+
+// Store caches module under key.
+func (mc *ModuleCache) Store(key string, module *object.Module) {
+	mc.modules[key] = module
+}
+
+func (e *evaluation) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	imported := e.importModule(node.Path.Value)
+	if isError(imported) {
+		return imported
+	}
+
+	module := imported.(*object.Module)
+	name := module.Name
+	if node.Alias != nil {
+		name = node.Alias.Value
+	}
+	env.Set(name, module)
+
+	return nil
+}
+
+// importModule returns the module for path, evaluating it in a fresh
+// environment the first time it is requested.
+func (e *evaluation) importModule(path string) object.Object {
+	key, src, err := ResolveModule(e.dir, path)
+	if err != nil {
+		return newError("cannot import %q: %s", path, err)
+	}
+
+	if module, ok := e.modules.Lookup(key); ok {
+		return module
+	}
+	if module, ok := NativeModule(key); ok {
+		e.modules.Store(key, module)
+		return module
+	}
+
+	for i, importing := range e.importing {
+		if importing == key {
+			cycle := append(append([]string{}, e.importing[i:]...), key)
+			return ImportCycleError(cycle)
+		}
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("cannot import %q: %s", path, strings.Join(p.Errors(), "; "))
+	}
+
+	dir := e.dir
+	e.dir = ModuleDir(key)
+	e.importing = append(e.importing, key)
+
+	env := object.NewEnvironment()
+	result := e.eval(program, env)
+
+	e.importing = e.importing[:len(e.importing)-1]
+	e.dir = dir
+
+	if isError(result) {
+		return result
+	}
+
+	name := strings.TrimSuffix(filepath.Base(key), ".zzz")
+	module := &object.Module{Name: name, Path: key, Env: env}
+	e.modules.Store(key, module)
+	return module
+}
+
+// ResolveModule resolves an import path, relative to dir, to the key modules are
+// cached under and the module's source. Standard modules are keyed by their
+// "std/..." name, files by their cleaned path. Native modules have no source.
+func ResolveModule(dir, path string) (string, string, error) {
+	if strings.HasPrefix(path, stdPrefix) {
+		key := strings.TrimSuffix(path, ".zzz")
+		if _, ok := nativeModules[key]; ok {
+			return key, "", nil
+		}
+		src, err := stdlib.ReadFile(key + ".zzz")
+		if err != nil {
+			return "", "", os.ErrNotExist
+		}
+		return key, string(src), nil
+	}
+
+	if filepath.Ext(path) == "" {
+		path += ".zzz"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Clean(path), string(src), nil
+}
+
+// NativeModule returns a fresh instance of the native module cached under key,
+// if there is one.
+func NativeModule(key string) (*object.Module, bool) {
+	members, ok := nativeModules[key]
+	if !ok {
+		return nil, false
+	}
+
+	env := object.NewEnvironment()
+	for name, member := range members {
+		env.Set(name, member)
+	}
+	return &object.Module{Name: filepath.Base(key), Path: key, Env: env}, true
+}
+
+// ModuleDir returns the directory imports inside the module cached under key
+// are resolved against, or "" for standard modules.
+func ModuleDir(key string) string {
+	if strings.HasPrefix(key, stdPrefix) {
+		return ""
+	}
+	return filepath.Dir(key)
+}
+
+// ImportCycleError reports the chain of module keys that imports itself.
+func ImportCycleError(cycle []string) *object.Error {
+	return newError("import cycle: %s", strings.Join(cycle, " -> "))
+}
+
+func evalModuleMember(module *object.Module, name string) object.Object {
+	if val, ok := module.Env.Get(name); ok {
+		return val
+	}
+	return newError("module %s has no member %q", module.Name, name)
+}
@@ -0,0 +1,219 @@
+package evaluator_test
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"math.zzz":       `lit square = fun(x) { x * x }; lit answer = 42; spit("loading math");`,
+		"lib/greet.zzz":  `import "../math.zzz"; lit hello = fun(n) { "hi " + n };`,
+		"broken.zzz":     `lit x = ;`,
+		"failing.zzz":    `lit x = 1 + yea;`,
+		"cycle/a.zzz":    `import "b.zzz";`,
+		"cycle/b.zzz":    `import "a";`,
+		"cycle/self.zzz": `import "self.zzz";`,
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`import "math.zzz"; math["square"](4)`, 16},
+		{`import "math" as m; m["answer"]`, 42},
+		{`import "lib/greet.zzz"; greet["hello"]("zzz")`, "hi zzz"},
+		{`import "math.zzz"; math["nope"]`, `module math has no member "nope"`},
+		{`import "broken.zzz"`, `cannot import "broken.zzz": no prefix parse function for ; found`},
+		{`import "failing.zzz"; 5`, "type mismatch: INTEGER + BOOLEAN"},
+		{`import "missing.zzz"`, `cannot import "missing.zzz": open ` + filepath.Join(dir, "missing.zzz") + `: no such file or directory`},
+		{`import "cycle/a.zzz"`, "import cycle: " + filepath.Join(dir, "cycle/a.zzz") + " -> " + filepath.Join(dir, "cycle/b.zzz") + " -> " + filepath.Join(dir, "cycle/a.zzz")},
+		{`import "cycle/self.zzz"`, "import cycle: " + filepath.Join(dir, "cycle/self.zzz") + " -> " + filepath.Join(dir, "cycle/self.zzz")},
+		{`import "std/strings" as s; s["join"](["a", "b", "c"], ", ")`, "a, b, c"},
+		{`import "std/strings"; strings["repeat"]("z", 3)`, "zzz"},
+		{`import "std/strings" as s; s.replace(s.trim(" a-b "), "-", "+")`, "a+b"},
+		{`import "math.zzz"; math.square(math.answer)`, 1764},
+		{`import "lib/greet.zzz" as g; g.hello("dots")`, "hi dots"},
+		{`import "std/nope"`, `cannot import "std/nope": file does not exist`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalModule(t, tt.input, evaluator.Options{Dir: dir})
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			var got string
+			switch obj := evaluated.(type) {
+			case *object.String:
+				got = obj.Value
+			case *object.Error:
+				got = obj.Message
+			default:
+				t.Errorf("unexpected object for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if got != expected {
+				t.Errorf("wrong result for %q.\nexpected=%q\ngot=%q", tt.input, expected, got)
+			}
+		}
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`math.pi`, `3.141592653589793`},
+		{`math.e`, `2.718281828459045`},
+		{`math.abs(-3)`, `3`},
+		{`math.abs(float("-2.5"))`, `2.5`},
+		{`math.min(3, 1, 2)`, `1`},
+		{`math.max([4, float("4.5"), 2])`, `4.5`},
+		{`math.pow(2, 10)`, `1024`},
+		{`math.pow(4, float("0.5"))`, `2.0`},
+		{`math.sqrt(16)`, `4.0`},
+		{`math.floor(float("2.7"))`, `2`},
+		{`math.floor(float("-2.5"))`, `-3`},
+		{`math.ceil(float("2.1"))`, `3`},
+		{`math.round(float("2.5"))`, `3`},
+		{`math.round(7)`, `7`},
+		{`math.sin(0) + math.cos(0)`, `1.0`},
+		{`math.tan(0) + math.asin(0) + math.acos(1)`, `0.0`},
+		{`math.atan(1) * 4 == math.pi`, `yea`},
+		{`math.atan(-1, -1) < 0`, `yea`},
+		{`math.exp(0)`, `1.0`},
+		{`math.log(math.e)`, `1.0`},
+		{`math.log(8, 2) + math.log(1000, 10)`, `6.0`},
+		{`math.gcd(12, -18)`, `6`},
+		{`math.gcd(0, 0)`, `0`},
+		{`math.lcm(4, 6)`, `12`},
+		{`math.lcm(0, 6)`, `0`},
+		{`math.sqrt("4")`, "argument to `sqrt` must be a number, got STRING"},
+		{`math.min()`, "`min` needs at least one number"},
+		{`math.max(1, "2")`, "argument to `max` must be a number, got STRING"},
+		{`math.gcd(4, float(2))`, "argument to `gcd` must be INTEGER, got FLOAT"},
+		{`math.log(1, 2, 3)`, "wrong number of arguments. got=3, want=1 or 2"},
+		{`math.floor(float("1e30"))`, `cannot convert 1e+30 to INTEGER`},
+		{`math.tau`, `module math has no member "tau"`},
+	}
+
+	for _, tt := range tests {
+		input := `import "std/math"; ` + tt.input
+		testInspect(t, input, testEvalModule(t, input, evaluator.Options{}), tt.expected)
+	}
+}
+
+func TestRandomModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lit r = random.rand(); !(r < 0) == (r < 1)`, `yea`},
+		{`lit n = random.randInt(3, 5); n > 2 == (n < 6)`, `yea`},
+		{`random.randInt(4, 4)`, `4`},
+		{`lit n = random.randInt(-9223372036854775807 - 1, 9223372036854775807); type(n)`, `int`},
+		{`lit c = random.choice(["a", "b"]); c == "a" == (c != "b")`, `yea`},
+		{`random.choice([7])`, `7`},
+		{`random.shuffle([1, 2, 3, 4]).sort()`, `[1, 2, 3, 4]`},
+		{`lit xs = [1, 2, 3]; random.shuffle(xs); xs`, `[1, 2, 3]`},
+		{`random.seed(1)`, `null`},
+		{`lit s = random.seed(1); type(s)`, `null`},
+		{`random.randInt(5, 4)`, "`randInt`: empty range 5 to 4"},
+		{`random.choice([])`, "`choice` from an empty ARRAY"},
+		{`random.shuffle("abc")`, "argument to `shuffle` must be ARRAY, got STRING"},
+		{`random.seed("x")`, "argument to `seed` must be INTEGER, got STRING"},
+		{`random.rand(1)`, "wrong number of arguments. got=1, want=0"},
+	}
+
+	for _, tt := range tests {
+		input := `import "std/random"; ` + tt.input
+		testInspect(t, input, testEvalModule(t, input, evaluator.Options{}), tt.expected)
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	// Seeded the same way, every engine draws the same numbers.
+	input := `import "std/random";
+random.seed(42);
+[random.rand(), random.randInt(1, 100), random.choice(["a", "b", "c"]), random.shuffle([1, 2, 3, 4, 5])]`
+	seeded := testEvalModule(t, input, evaluator.Options{})
+	if again := testEvalModule(t, input, evaluator.Options{}); again.Inspect() != seeded.Inspect() {
+		t.Errorf("seed(42) gave different numbers. %s != %s", seeded.Inspect(), again.Inspect())
+	}
+
+	// So does a generator the host seeds through the options.
+	input = `import "std/random"; [random.randInt(1, 1000000), random.randInt(1, 1000000)]`
+	var expected string
+	for _, engine := range engines {
+		opts := evaluator.Options{Random: rand.New(rand.NewSource(42))}
+		got := engine.run(context.Background(), parser.New(lexer.New(input)).ParseProgram(), nil, opts).Inspect()
+		if expected == "" {
+			expected = got
+		} else if got != expected {
+			t.Errorf("%s: wrong numbers for the same seed. got=%s, expected=%s", engine.name, got, expected)
+		}
+	}
+}
+
+func TestImportsAreCached(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"counter.zzz": `spit("evaluated"); lit value = 1;`,
+		"user.zzz":    `import "counter.zzz"; lit value = counter["value"] + 1;`,
+	})
+
+	var out bytes.Buffer
+	opts := evaluator.Options{Dir: dir, Stdout: &out, Modules: evaluator.NewModuleCache()}
+
+	testIntegerObject(t, testEvalModule(t, `import "counter.zzz"; import "user.zzz"; user["value"]`, opts), 2)
+	testIntegerObject(t, testEvalModule(t, `import "counter.zzz" as c; c["value"]`, opts), 1)
+
+	if out.String() != "evaluated\n" {
+		t.Errorf("module evaluated more than once. stdout=%q", out.String())
+	}
+}
+
+func TestModulesHaveOwnEnvironment(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"leaky.zzz": `lit secret = 1; lit peek = fun() { outer };`,
+	})
+
+	input := `lit outer = 5; import "leaky.zzz"; leaky["peek"]()`
+	evaluated := testEvalModule(t, input, evaluator.Options{Dir: dir})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "undefined identifier: outer" {
+		t.Errorf("module saw the importer's bindings. got=%+v", evaluated)
+	}
+}
+
+func testEvalModule(t *testing.T, input string, opts evaluator.Options) object.Object {
+	t.Helper()
+	return testEngines(t, context.Background(), input, opts, nil)
+}
+
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.TrimSpace(src)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
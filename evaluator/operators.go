@@ -0,0 +1,52 @@
+package evaluator
+
+import "github.com/amirhesham65/zzz-lang/object"
+
+// The functions below expose the evaluator's operator semantics so that other
+// engines, such as the bytecode VM, produce the same values and error messages.
+
+// Prefix applies a prefix operator ("!" or "-") to right.
+func Prefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// Infix applies a binary operator to left and right.
+func Infix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// Index evaluates left[index].
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// Slice evaluates left[start:end]; NULL stands for an omitted bound.
+func Slice(left, start, end object.Object) object.Object {
+	return evalSliceExpression(left, start, end)
+}
+
+// Concat joins values into a string the way template strings do.
+func Concat(values []object.Object) object.Object {
+	return concat(values)
+}
+
+// Member evaluates obj.name, including method lookup.
+func Member(obj object.Object, name string) object.Object {
+	return evalMemberExpression(obj, name)
+}
+
+// Truthy reports whether obj counts as true in a condition.
+func Truthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+// NewError builds a runtime error object the way the evaluator does.
+func NewError(format string, a ...any) *object.Error {
+	return newError(format, a...)
+}
+
+// SizeOf approximates the bytes held directly by obj, as charged against
+// Options.MaxMemory.
+func SizeOf(obj object.Object) int64 {
+	return sizeOf(obj)
+}
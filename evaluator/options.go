@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Options configures a single evaluation. A zero value for any limit means that
+// resource is unlimited, except for MaxDepth, and nil streams or builtins fall
+// back to the process defaults.
+type Options struct {
+	MaxSteps  int64         // maximum number of AST nodes evaluated
+	MaxMemory int64         // approximate number of bytes the evaluation may allocate in total
+	MaxDepth  int           // maximum nesting of user function calls; 0 means DefaultMaxDepth
+	Timeout   time.Duration // wall-clock limit, applied on top of the context's own deadline
+
+	Builtins map[string]*object.Builtin // builtins visible to the program, see NewBuiltins
+	Stdout   io.Writer
+	Stderr   io.Writer
+	Stdin    io.Reader
+	Random   *rand.Rand // generator std/random draws from; nil gives each evaluation its own
+
+	Dir     string       // directory relative imports are resolved against; "" means the working directory
+	Modules *ModuleCache // modules imported so far; nil gives each evaluation its own cache
+
+	Literals map[ast.Node]object.Object // objects reused for literal nodes, see optimizer.Literals
+}
+
+// DefaultMaxDepth bounds the nesting of user function calls when
+// Options.MaxDepth is 0. Unbounded recursion would overflow the Go stack, which
+// kills the process instead of returning an error.
+const DefaultMaxDepth = 10000
+
+// Depth returns the call depth limit in effect for o.
+func (o Options) Depth() int {
+	if o.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return o.MaxDepth
+}
+
+// evaluation carries the state of one call to EvalContext through the tree walk.
+// It is also the object.Runtime handed to builtin functions.
+type evaluation struct {
+	ctx     context.Context
+	opts    Options
+	steps   int64
+	memory  int64
+	depth   int
+	stopped *object.Error // set once a budget is exhausted; every later step returns it
+
+	dir       string   // directory of the file being evaluated
+	importing []string // modules currently being evaluated, outermost first
+	modules   *ModuleCache
+}
+
+// EvalContext evaluates node like Eval, but stops as soon as ctx is done or any of
+// the budgets in opts is exhausted. In that case the returned *object.Error has a
+// Kind for which IsLimit reports true. Evaluation never modifies node, so the
+// same tree can be evaluated by several goroutines at once.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Environment, opts Options) object.Object {
+	e, cancel := newEvaluation(ctx, opts)
+	defer cancel()
+
+	return e.eval(node, env)
+}
+
+// ApplyContext calls fn, a user function or builtin, with args under the same
+// budgets and streams as EvalContext.
+func ApplyContext(ctx context.Context, fn object.Object, args []object.Object, opts Options) object.Object {
+	e, cancel := newEvaluation(ctx, opts)
+	defer cancel()
+
+	return e.applyFunction(fn, args)
+}
+
+func newEvaluation(ctx context.Context, opts Options) (*evaluation, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	modules := opts.Modules
+	if modules == nil {
+		modules = NewModuleCache()
+	}
+	return &evaluation{ctx: ctx, opts: opts, dir: opts.Dir, modules: modules}, cancel
+}
+
+func (e *evaluation) builtins() map[string]*object.Builtin {
+	if e.opts.Builtins != nil {
+		return e.opts.Builtins
+	}
+	return builtins
+}
+
+func (e *evaluation) Call(fn object.Object, args ...object.Object) object.Object {
+	if result := e.applyFunction(fn, args); result != nil {
+		return result
+	}
+	return NULL
+}
+
+func (e *evaluation) Random() *rand.Rand {
+	if e.opts.Random == nil {
+		e.opts.Random = NewRandom()
+	}
+	return e.opts.Random
+}
+
+// NewRandom returns a generator with an unpredictable seed.
+func NewRandom() *rand.Rand {
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+func (e *evaluation) Stdout() io.Writer {
+	if e.opts.Stdout != nil {
+		return e.opts.Stdout
+	}
+	return os.Stdout
+}
+
+func (e *evaluation) Stderr() io.Writer {
+	if e.opts.Stderr != nil {
+		return e.opts.Stderr
+	}
+	return os.Stderr
+}
+
+func (e *evaluation) Stdin() io.Reader {
+	if e.opts.Stdin != nil {
+		return e.opts.Stdin
+	}
+	return os.Stdin
+}
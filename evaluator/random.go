@@ -0,0 +1,87 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// randomModule holds the members of std/random. They draw from the runtime's
+// generator, which hosts can seed through Options.Random to make runs
+// reproducible.
+var randomModule = map[string]object.Object{
+	"rand":    &object.Builtin{Fn: randomFloat},
+	"randInt": &object.Builtin{Fn: randomInt},
+	"choice":  &object.Builtin{Fn: randomChoice},
+	"shuffle": &object.Builtin{Fn: randomShuffle},
+	"seed":    &object.Builtin{Fn: randomSeed},
+}
+
+// randomFloat returns a float in [0, 1).
+func randomFloat(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return &object.Float{Value: rt.Random().Float64()}
+}
+
+// randomInt is randInt(lo, hi): an integer between lo and hi, both included.
+func randomInt(rt object.Runtime, args ...object.Object) object.Object {
+	lo, hi, err := twoIntegers("randInt", args)
+	if err != nil {
+		return err
+	}
+	if lo > hi {
+		return newError("`randInt`: empty range %d to %d", lo, hi)
+	}
+
+	// hi - lo may overflow an int64, but never a uint64.
+	r, span := rt.Random(), uint64(hi-lo)
+	if span < math.MaxInt64 {
+		return &object.Integer{Value: lo + r.Int63n(int64(span)+1)}
+	}
+	for {
+		if n := r.Uint64(); n <= span {
+			return &object.Integer{Value: lo + int64(n)}
+		}
+	}
+}
+
+func randomChoice(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("choice", args)
+	if err != nil {
+		return err
+	}
+	if len(arr.Elements) == 0 {
+		return newError("`choice` from an empty ARRAY")
+	}
+	return arr.Elements[rt.Random().Intn(len(arr.Elements))]
+}
+
+// randomShuffle returns a shuffled copy of an array.
+func randomShuffle(rt object.Runtime, args ...object.Object) object.Object {
+	arr, err := oneArray("shuffle", args)
+	if err != nil {
+		return err
+	}
+
+	elements := append([]object.Object{}, arr.Elements...)
+	rt.Random().Shuffle(len(elements), func(i, j int) {
+		elements[i], elements[j] = elements[j], elements[i]
+	})
+	return &object.Array{Elements: elements}
+}
+
+// randomSeed restarts the generator from seed, so the numbers drawn after it
+// are the same on every run.
+func randomSeed(rt object.Runtime, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	seed, ok := args[0].(*object.Integer)
+	if !ok {
+		return newError("argument to `seed` must be INTEGER, got %s", args[0].Type())
+	}
+	rt.Random().Seed(seed.Value)
+	return NULL
+}
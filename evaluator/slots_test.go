@@ -0,0 +1,55 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+const fib = `
+lit fib = fun(n) {
+	fr (n < 2) { return n; };
+	fib(n - 1) + fib(n - 2)
+};
+fib(20)`
+
+// BenchmarkFib compares slot-indexed frames against looking every variable
+// up by name, which is what evaluating an unresolved program does.
+func BenchmarkFib(b *testing.B) {
+	b.Run("resolved", func(b *testing.B) {
+		program := parse(b, fib)
+		for i := 0; i < b.N; i++ {
+			Eval(program, object.NewEnvironment())
+		}
+	})
+
+	b.Run("by-name", func(b *testing.B) {
+		program := parse(b, fib)
+		ast.Inspect(program, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Identifier:
+				node.Local = false
+			case *ast.FunctionLiteral:
+				node.Locals = nil
+			}
+			return true
+		})
+		for i := 0; i < b.N; i++ {
+			Eval(program, object.NewEnvironment())
+		}
+	})
+}
+
+func parse(t testing.TB, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
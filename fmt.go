@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/format"
+)
+
+// fmtCommand formats programs. Without files, it formats standard input.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("zzz fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of standard output")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: zzz fmt [-w] [-d] [file.zzz...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := formatSource("<stdin>", src, false, *diff); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err == nil {
+			err = formatSource(path, src, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// formatSource formats the source of the named file and writes it back, prints
+// a diff, or prints the result, in that order of preference.
+func formatSource(name string, src []byte, write, diff bool) error {
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case write:
+		if bytes.Equal(src, out) {
+			return nil
+		}
+		return os.WriteFile(name, out, 0o644)
+	case diff:
+		if !bytes.Equal(src, out) {
+			fmt.Print(lineDiff(name, string(src), string(out)))
+		}
+		return nil
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// lineDiff returns the changes from before to after as a diff of whole lines,
+// with every line of both versions marked as kept (" "), removed ("-") or
+// added ("+").
+func lineDiff(name, before, after string) string {
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,480 @@
+// Package format prints ZZZ programs in their canonical layout.
+package format
+
+import (
+	"bytes"
+	"math"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/parser"
+	"github.com/amirhesham65/zzz-lang/token"
+)
+
+// indent is the text each nesting level of blocks is indented with.
+const indent = "    "
+
+// Error reports source that cannot be formatted because it does not parse.
+type Error struct {
+	Errors []string
+}
+
+func (e *Error) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// Source formats a program. Every statement goes on its own line and ends with
+// a semicolon, blocks are indented, and expressions get only the parentheses
+// the parser's precedences require. Single blank lines between statements are
+// kept, and so is a block written on one line if it holds a single simple
+// expression, like `fun(x) { x * 2 }`.
+//
+// Comments are kept next to the code they precede or follow on the same line.
+// A list, call or hash literal with comments among its elements is printed
+// with one element per line, so that each comment stays with its element. A
+// comment inside any other expression spanning several lines moves in front
+// of the next statement. Formatting formatted source returns it unchanged.
+func Source(src []byte) ([]byte, error) {
+	l := lexer.New(string(src))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &Error{Errors: p.Errors()}
+	}
+
+	pr := &printer{
+		comments: l.Comments(),
+		lines:    strings.Split(string(src), "\n"),
+	}
+	pr.statements(program.Statements, math.MaxInt)
+	return pr.buf.Bytes(), nil
+}
+
+type printer struct {
+	buf      bytes.Buffer
+	depth    int
+	comments []token.Comment // comments not printed yet
+	lines    []string        // lines of the source
+	trailing *token.Comment  // comment to print at the end of the current line
+}
+
+// newline ends the current line, with the pending trailing comment.
+func (p *printer) newline() {
+	if p.trailing != nil {
+		p.buf.WriteString(" " + p.trailing.Text)
+		p.trailing = nil
+	}
+	p.buf.WriteByte('\n')
+}
+
+func (p *printer) writeIndent() {
+	p.buf.WriteString(strings.Repeat(indent, p.depth))
+}
+
+// blank reports whether the given source line is empty.
+func (p *printer) blank(line int) bool {
+	return line >= 1 && line <= len(p.lines) && strings.TrimSpace(p.lines[line-1]) == ""
+}
+
+// statements prints a statement list, one statement per line, together with
+// the comments that start before line end.
+func (p *printer) statements(stmts []ast.Statement, end int) {
+	prev := 0 // line of the previous item, 0 before the first
+	item := func(line int) {
+		if prev != 0 && line > prev && p.blank(line-1) {
+			p.buf.WriteByte('\n')
+		}
+		prev = line
+		p.writeIndent()
+	}
+	comments := func(before int) {
+		for len(p.comments) > 0 && p.comments[0].Line < before {
+			item(p.comments[0].Line)
+			p.buf.WriteString(p.comments[0].Text)
+			p.comments = p.comments[1:]
+			p.newline()
+		}
+	}
+
+	for i, stmt := range stmts {
+		line := startLine(stmt)
+		comments(line)
+		item(line)
+
+		next := end
+		if i < len(stmts)-1 {
+			next = startLine(stmts[i+1])
+		}
+
+		p.statement(stmt)
+		p.buf.WriteByte(';')
+		if last := p.lastCodeLine(line, next); last != next {
+			p.trailLast(last)
+		}
+		p.newline()
+	}
+	comments(end)
+}
+
+// lastCodeLine returns the last line holding code from first up to but not
+// including limit, which is where a statement starting on first ends.
+func (p *printer) lastCodeLine(first, limit int) int {
+	last := first
+	for line := first + 1; line < limit && line <= len(p.lines); line++ {
+		text := strings.TrimSpace(p.lines[line-1])
+		if text != "" && !strings.HasPrefix(text, "//") {
+			last = line
+		}
+	}
+	return last
+}
+
+// trailLast makes the comment on line, the last of a statement, the
+// statement's trailing comment. Comments still pending from the lines before
+// were inside an expression printed on one line, so they stay in the queue and
+// move in front of the next statement.
+func (p *printer) trailLast(line int) {
+	for i, c := range p.comments {
+		if c.Line > line {
+			return
+		}
+		if c.Line == line {
+			p.comments = append(p.comments[:i:i], p.comments[i+1:]...)
+			p.trailing = &c
+			return
+		}
+	}
+}
+
+// interject prints the comments not printed yet that start before line, in
+// the middle of an expression. The first stays at the end of the current line
+// if code comes before it in the source, the others go on lines of their own.
+// It reports whether it printed any.
+func (p *printer) interject(line int) bool {
+	printed := false
+	for p.commentsBefore(line) {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if printed || !p.afterCode(c) {
+			p.buf.WriteByte('\n')
+			p.writeIndent()
+		} else {
+			p.buf.WriteByte(' ')
+		}
+		p.buf.WriteString(c.Text)
+		printed = true
+	}
+	return printed
+}
+
+// afterCode reports whether code comes before c on its line of the source.
+func (p *printer) afterCode(c token.Comment) bool {
+	text := p.lines[c.Line-1]
+	return strings.TrimSpace(text[:min(c.Column-1, len(text))]) != ""
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.buf.WriteString("lit " + declaration(stmt.Name) + " = ")
+		p.expression(stmt.Value, parser.LOWEST)
+	case *ast.ReturnStatement:
+		p.buf.WriteString("return")
+		if stmt.ReturnValue != nil {
+			p.buf.WriteByte(' ')
+			p.expression(stmt.ReturnValue, parser.LOWEST)
+		}
+	case *ast.ImportStatement:
+		p.buf.WriteString(`import "` + stmt.Path.Value + `"`)
+		if stmt.Alias != nil {
+			p.buf.WriteString(" as " + stmt.Alias.Value)
+		}
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, parser.LOWEST)
+	}
+}
+
+// expression prints expr, in parentheses if it binds less tightly than
+// required by its context.
+func (p *printer) expression(expr ast.Expression, required int) {
+	if precedence(expr) < required {
+		p.buf.WriteByte('(')
+		defer p.buf.WriteByte(')')
+	}
+
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		p.buf.WriteString(expr.Value)
+	case *ast.IntegerLiteral:
+		p.buf.WriteString(expr.Token.Literal)
+	case *ast.StringLiteral:
+		p.buf.WriteString(`"` + expr.Value + `"`)
+	case *ast.TemplateLiteral:
+		p.buf.WriteByte('"')
+		for i, embedded := range expr.Exprs {
+			p.buf.WriteString(expr.Texts[i] + "${")
+			p.expression(embedded, parser.LOWEST)
+			p.buf.WriteByte('}')
+		}
+		p.buf.WriteString(expr.Texts[len(expr.Exprs)] + `"`)
+	case *ast.Boolean:
+		p.buf.WriteString(expr.Token.Literal)
+	case *ast.PrefixExpression:
+		p.buf.WriteString(expr.Operator)
+		if right, ok := expr.Right.(*ast.PrefixExpression); ok && right.Operator == "-" && expr.Operator == "-" {
+			// Keep `-(-x)` from reading as a decrement.
+			p.expression(right, parser.INDEX+1)
+			break
+		}
+		p.expression(expr.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// Operators are left-associative, so only the right operand needs
+		// parentheses at the same precedence. `**` is the exception: it is
+		// right-associative and its right operand may be a prefix expression.
+		prec := parser.Precedence(expr.Token.Type)
+		left, right := prec, prec+1
+		if expr.Token.Type == token.POWER {
+			left, right = prec+1, parser.PREFIX
+		}
+		p.expression(expr.Left, left)
+		p.buf.WriteString(" " + expr.Operator + " ")
+		p.expression(expr.Right, right)
+	case *ast.CallExpression:
+		p.expression(expr.Function, parser.CALL)
+		p.list(expr.Token, expr.Arguments, expr.End)
+	case *ast.IndexExpression:
+		p.expression(expr.Left, parser.INDEX)
+		p.buf.WriteByte('[')
+		p.expression(expr.Index, parser.LOWEST)
+		p.buf.WriteByte(']')
+	case *ast.SliceExpression:
+		p.expression(expr.Left, parser.INDEX)
+		p.buf.WriteByte('[')
+		if expr.Start != nil {
+			p.expression(expr.Start, parser.LOWEST)
+		}
+		p.buf.WriteByte(':')
+		if expr.End != nil {
+			p.expression(expr.End, parser.LOWEST)
+		}
+		p.buf.WriteByte(']')
+	case *ast.MemberExpression:
+		p.expression(expr.Object, parser.INDEX)
+		p.buf.WriteString("." + expr.Property.Value)
+	case *ast.ArrayLiteral:
+		p.list(expr.Token, expr.Elements, expr.End)
+	case *ast.HashLiteral:
+		p.hash(expr)
+	case *ast.FunctionLiteral:
+		params := make([]string, len(expr.Parameters))
+		for i, param := range expr.Parameters {
+			params[i] = declaration(param)
+		}
+		p.buf.WriteString("fun(" + strings.Join(params, ", ") + ") ")
+		if expr.ReturnType != nil {
+			p.buf.WriteString("-> " + expr.ReturnType.Name + " ")
+		}
+		p.block(expr.Body)
+	case *ast.IfExpression:
+		p.buf.WriteString("fr (")
+		p.expression(expr.Condition, parser.LOWEST)
+		p.buf.WriteString(") ")
+		p.block(expr.Consequence)
+		if expr.Alternative != nil {
+			// A comment after the consequence stays after its closing brace.
+			if p.interject(expr.Alternative.Token.Line) {
+				p.buf.WriteByte('\n')
+				p.writeIndent()
+			} else {
+				p.buf.WriteByte(' ')
+			}
+			p.buf.WriteString("lowkey ")
+			p.block(expr.Alternative)
+		}
+	}
+}
+
+// list prints exprs between the brackets open and end, separated by commas.
+func (p *printer) list(open token.Token, exprs []ast.Expression, end token.Token) {
+	p.elements(open, end, len(exprs), func(i int) int { return startLineOf(exprs[i]) }, func(i int) {
+		p.expression(exprs[i], parser.LOWEST)
+	})
+}
+
+// hash prints a hash literal with its pairs in source order.
+func (p *printer) hash(hash *ast.HashLiteral) {
+	p.elements(hash.Token, hash.End, len(hash.Pairs), func(i int) int { return startLineOf(hash.Pairs[i].Key) }, func(i int) {
+		p.expression(hash.Pairs[i].Key, parser.LOWEST)
+		p.buf.WriteString(": ")
+		p.expression(hash.Pairs[i].Value, parser.LOWEST)
+	})
+}
+
+// elements prints n elements between the brackets open and end, separated by
+// commas, on one line. If there are comments between the brackets, every
+// element goes on a line of its own instead, with the comments around it where
+// they were in the source. line returns the line element i starts on.
+func (p *printer) elements(open, end token.Token, n int, line func(i int) int, element func(i int)) {
+	p.buf.WriteString(open.Literal)
+	if !p.commentsWithin(open, end) {
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.buf.WriteString(", ")
+			}
+			element(i)
+		}
+		p.buf.WriteString(end.Literal)
+		return
+	}
+
+	p.depth++
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.buf.WriteByte(',')
+		}
+		p.interject(line(i))
+		p.buf.WriteByte('\n')
+		p.writeIndent()
+		element(i)
+	}
+	p.interject(end.Line)
+	p.depth--
+	p.buf.WriteByte('\n')
+	p.writeIndent()
+	p.buf.WriteString(end.Literal)
+}
+
+// block prints a block, on one line if it was written on one line and holds
+// a single simple expression, otherwise indented over several lines.
+func (p *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 && !p.commentsBefore(block.End.Line) {
+		p.buf.WriteString("{}")
+		return
+	}
+
+	if block.Token.Line == block.End.Line && len(block.Statements) == 1 {
+		if stmt, ok := block.Statements[0].(*ast.ExpressionStatement); ok && simple(stmt.Expression) {
+			p.buf.WriteString("{ ")
+			p.expression(stmt.Expression, parser.LOWEST)
+			p.buf.WriteString(" }")
+			return
+		}
+	}
+
+	p.buf.WriteByte('{')
+	if len(p.comments) > 0 && p.comments[0].Line == block.Token.Line {
+		// A comment after the opening brace stays there.
+		p.trailing = &p.comments[0]
+		p.comments = p.comments[1:]
+	}
+	p.newline()
+	p.depth++
+	p.statements(block.Statements, block.End.Line)
+	p.depth--
+	p.writeIndent()
+	p.buf.WriteByte('}')
+}
+
+// commentsBefore reports whether a comment not printed yet starts before line.
+func (p *printer) commentsBefore(line int) bool {
+	return len(p.comments) > 0 && p.comments[0].Line < line
+}
+
+// commentsWithin reports whether the next comment not printed yet is between
+// the tokens open and end.
+func (p *printer) commentsWithin(open, end token.Token) bool {
+	if len(p.comments) == 0 {
+		return false
+	}
+	c := p.comments[0]
+	after := c.Line > open.Line || c.Line == open.Line && c.Column > open.Column
+	return after && c.Line < end.Line
+}
+
+// simple reports whether expr contains no blocks.
+func simple(expr ast.Expression) bool {
+	blocks := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.BlockStatement); ok {
+			blocks = true
+		}
+		return !blocks
+	})
+	return !blocks
+}
+
+// precedence returns how tightly expr binds, in terms of parser precedences.
+// Anything that is not an operator binds tighter than every operator.
+func precedence(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(expr.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.SliceExpression, *ast.MemberExpression:
+		return parser.INDEX
+	}
+	return parser.INDEX + 1
+}
+
+func declaration(name *ast.Identifier) string {
+	if name.Type == nil {
+		return name.Value
+	}
+	return name.Value + ": " + name.Type.Name
+}
+
+func startLine(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Line
+	case *ast.ImportStatement:
+		return stmt.Token.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Line
+	}
+	return 0
+}
+
+// startLineOf returns the line expr starts on, which for an operator, call or
+// index is the line of its leftmost operand.
+func startLineOf(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return startLineOf(expr.Left)
+	case *ast.CallExpression:
+		return startLineOf(expr.Function)
+	case *ast.IndexExpression:
+		return startLineOf(expr.Left)
+	case *ast.SliceExpression:
+		return startLineOf(expr.Left)
+	case *ast.MemberExpression:
+		return startLineOf(expr.Object)
+	case *ast.Identifier:
+		return expr.Token.Line
+	case *ast.IntegerLiteral:
+		return expr.Token.Line
+	case *ast.StringLiteral:
+		return expr.Token.Line
+	case *ast.TemplateLiteral:
+		return expr.Token.Line
+	case *ast.Boolean:
+		return expr.Token.Line
+	case *ast.PrefixExpression:
+		return expr.Token.Line
+	case *ast.IfExpression:
+		return expr.Token.Line
+	case *ast.FunctionLiteral:
+		return expr.Token.Line
+	case *ast.ArrayLiteral:
+		return expr.Token.Line
+	case *ast.HashLiteral:
+		return expr.Token.Line
+	}
+	return 0
+}
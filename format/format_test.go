@@ -0,0 +1,102 @@
+package format
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestSourceGolden formats every testdata/*.input program and compares the
+// result with the .golden file next to it. Run the tests with -update after an
+// intended change in formatting.
+func TestSourceGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.input"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no golden inputs found: %v", err)
+	}
+
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := Source(src)
+		if err != nil {
+			t.Fatalf("%s: Source returned error: %s", path, err)
+		}
+
+		golden := strings.TrimSuffix(path, ".input") + ".golden"
+		if *update {
+			if err := os.WriteFile(golden, out, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%s: %s (run with -update to create it)", path, err)
+		}
+		if string(out) != string(expected) {
+			t.Errorf("%s: output differs from %s.\nwant:\n%s\ngot:\n%s", path, golden, expected, out)
+		}
+
+		again, err := Source(out)
+		if err != nil {
+			t.Fatalf("%s: formatted output does not parse: %s", path, err)
+		}
+		if string(again) != string(out) {
+			t.Errorf("%s: formatting is not idempotent.\nfirst:\n%s\nsecond:\n%s", path, out, again)
+		}
+	}
+}
+
+func TestSourceParentheses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(1 + 2) + 3", "1 + 2 + 3;\n"},
+		{"1 + (2 + 3)", "1 + (2 + 3);\n"},
+		{"1 - (2 - 3)", "1 - (2 - 3);\n"},
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"1 + (2 * 3)", "1 + 2 * 3;\n"},
+		{"-(1 + 2)", "-(1 + 2);\n"},
+		{"-(-1)", "-(-1);\n"},
+		{"!!a", "!!a;\n"},
+		{"!(a < b)", "!(a < b);\n"},
+		{"(a < b) == (c > d)", "a < b == c > d;\n"},
+		{"(-a) * b", "-a * b;\n"},
+		{"(f)(1)", "f(1);\n"},
+		{"(f(1))(2)", "f(1)(2);\n"},
+		{"(fun(x) { x })(1)", "fun(x) { x }(1);\n"},
+		{"(a + b)[0]", "(a + b)[0];\n"},
+		{"(a[0]).b", "a[0].b;\n"},
+		{"(-a).b", "(-a).b;\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("%q: Source returned error: %s", tt.input, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.input, tt.expected, out)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	_, err := Source([]byte("lit = 1;"))
+	if err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected *Error, got %T (%v)", err, err)
+	}
+}
@@ -2,6 +2,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/amirhesham65/zzz-lang/token"
 )
 
@@ -11,17 +13,32 @@ type Lexer struct {
 	position     int    // current position in input (points to current char)
 	readPosition int    // current reading position in input (after current char)
 	ch           byte   // current char being read
+	line         int    // line of the current char, starting at 1
+	lineStart    int    // position of the first char of the current line
+
+	comments []token.Comment // comments skipped so far
 }
 
 // New initializes a new instance of Lexer with the input string.
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewAt(input, 1, 1)
+}
+
+// NewAt is like New for input that starts at the given line and column of a
+// larger source, such as an expression embedded in a template string, so that
+// tokens carry their positions in that source.
+func NewAt(input string, line, column int) *Lexer {
+	l := &Lexer{input: input, line: line, lineStart: 1 - column}
 	l.readChar()
 	return l
 }
 
 // readChar reads the next character from the input and advances the position.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.readPosition
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // ASCII code for the "NUL"
 	} else {
@@ -49,16 +66,84 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
-// readString reads a string from the input.
-func (l *Lexer) readString() string {
+// readString reads a string from the input and reports whether it embeds
+// `${...}` expressions. An embedded expression may contain strings itself.
+func (l *Lexer) readString() (string, bool) {
 	position := l.position + 1
+	template := false
 	for {
 		l.readChar()
+		if l.ch == '$' && l.peakChar() == '{' {
+			template = true
+			l.readChar()
+			l.skipEmbedded()
+			if l.ch == 0 {
+				break
+			}
+			continue
+		}
 		if l.ch == '"' || l.ch == 0 {
 			break
 		}
 	}
-	return l.input[position:l.position]
+	return l.input[position:l.position], template
+}
+
+// skipEmbedded advances from the `{` that opens an embedded expression to the
+// `}` that closes it, passing over nested braces and strings.
+func (l *Lexer) skipEmbedded() {
+	depth := 0
+	for ; l.ch != 0; l.readChar() {
+		switch l.ch {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return
+			}
+		case '"':
+			if l.readString(); l.ch == 0 {
+				return
+			}
+		}
+	}
+}
+
+// TemplatePart is a piece of the literal of a TEMPLATE token: text, or the
+// source of an embedded expression without its `${` and `}`.
+type TemplatePart struct {
+	Text   string
+	Expr   bool
+	Offset int // byte offset of Text in the literal
+}
+
+// SplitTemplate splits the literal of a TEMPLATE token into text and embedded
+// expressions, which alternate starting and ending with text, so there is one
+// more text part than expressions. It returns false if an embedded expression
+// is not closed.
+func SplitTemplate(literal string) ([]TemplatePart, bool) {
+	l := New(literal)
+	var parts []TemplatePart
+	start := 0
+	for l.ch != 0 {
+		if l.ch != '$' || l.peakChar() != '{' {
+			l.readChar()
+			continue
+		}
+
+		parts = append(parts, TemplatePart{Text: literal[start:l.position], Offset: start})
+		l.readChar()
+		open := l.position + 1
+		l.skipEmbedded()
+		if l.ch == 0 {
+			return nil, false
+		}
+		parts = append(parts, TemplatePart{Text: literal[open:l.position], Expr: true, Offset: open})
+		l.readChar()
+		start = l.position
+	}
+	parts = append(parts, TemplatePart{Text: literal[start:], Offset: start})
+	return parts, true
 }
 
 // peakChar returns the next character in the input without consuming it.
@@ -69,17 +154,41 @@ func (l *Lexer) peakChar() byte {
 	return l.input[l.readPosition]
 }
 
-// eatWhitespace skips over whitespace characters in the input.
+// eatWhitespace skips over whitespace characters and comments in the input.
 func (l *Lexer) eatWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+			l.readChar()
+		}
+		if l.ch != '/' || l.peakChar() != '/' {
+			return
+		}
+		l.readComment()
+	}
+}
+
+// readComment reads a `//` comment up to the end of the line and records it.
+func (l *Lexer) readComment() {
+	comment := token.Comment{Line: l.line, Column: l.position - l.lineStart + 1}
+	position := l.position
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+	comment.Text = strings.TrimRight(l.input[position:l.position], " \t\r")
+	l.comments = append(l.comments, comment)
+}
+
+// Comments returns the comments skipped so far, in source order. Once the
+// parser has consumed every token, these are all the comments of the input.
+func (l *Lexer) Comments() []token.Comment {
+	return l.comments
 }
 
 // NextToken returns the next token from the input.
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.eatWhitespace()
+	line, column := l.line, l.position-l.lineStart+1
 
 	switch l.ch {
 	case '=':
@@ -94,9 +203,23 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = token.NewToken(token.PLUS, l.ch)
 	case '-':
-		tok = token.NewToken(token.MINUS, l.ch)
+		if l.peakChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok.Type = token.ARROW
+			tok.Literal = string(ch) + string(l.ch)
+		} else {
+			tok = token.NewToken(token.MINUS, l.ch)
+		}
 	case '*':
-		tok = token.NewToken(token.ASTERISK, l.ch)
+		if l.peakChar() == '*' {
+			ch := l.ch
+			l.readChar()
+			tok.Type = token.POWER
+			tok.Literal = string(ch) + string(l.ch)
+		} else {
+			tok = token.NewToken(token.ASTERISK, l.ch)
+		}
 	case '/':
 		tok = token.NewToken(token.SLASH, l.ch)
 	case '!':
@@ -129,21 +252,29 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Type = token.EOF
 	case '"':
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		var template bool
+		tok.Literal, template = l.readString()
+		if template {
+			tok.Type = token.TEMPLATE
+		}
 	case '[':
 		tok = token.NewToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = token.NewToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = token.NewToken(token.COLON, l.ch)
+	case '.':
+		tok = token.NewToken(token.DOT, l.ch)
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookUpIndent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
 			tok.Type = token.INT
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = token.NewToken(token.ILLEGAL, l.ch)
@@ -151,6 +282,7 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
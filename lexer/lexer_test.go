@@ -32,6 +32,7 @@ func TestNextToken(t *testing.T) {
 		[1, 2];
 
 		{"foo": "bar"}
+		person.name
 	`
 
 	tests := []struct {
@@ -124,6 +125,9 @@ func TestNextToken(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.IDENT, "person"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
 		{token.EOF, ""},
 	}
 
@@ -141,3 +145,173 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "lit x = 5;\n  fr (x == 5) {\n\t\"a\nb\" }"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"lit", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"fr", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{"==", 2, 9},
+		{"5", 2, 12},
+		{")", 2, 13},
+		{"{", 2, 15},
+		{"a\nb", 3, 2},
+		{"}", 4, 4},
+		{"", 4, 5},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLiteral, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestArrowToken(t *testing.T) {
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.MINUS, "-"},
+		{token.GT, ">"},
+		{token.EOF, ""},
+	}
+
+	l := New(") -> int - >")
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestPowerToken(t *testing.T) {
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.POWER, "**"},
+		{token.ASTERISK, "*"},
+		{token.ASTERISK, "*"},
+		{token.EOF, ""},
+	}
+
+	l := New("2**3 *** *")
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "// header\nlit x = 5; // five\nx / 2 //no space  \n//"
+
+	expectedTokens := []string{"lit", "x", "=", "5", ";", "x", "/", "2", ""}
+	l := New(input)
+	for i, expected := range expectedTokens {
+		if tok := l.NextToken(); tok.Literal != expected {
+			t.Fatalf("tests[%d] - wrong token. expected=%q, got=%q", i, expected, tok.Literal)
+		}
+	}
+
+	expectedComments := []token.Comment{
+		{Text: "// header", Line: 1, Column: 1},
+		{Text: "// five", Line: 2, Column: 12},
+		{Text: "//no space", Line: 3, Column: 7},
+		{Text: "//", Line: 4, Column: 1},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d (%v)", len(expectedComments), len(comments), comments)
+	}
+	for i, expected := range expectedComments {
+		if comments[i] != expected {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expected, comments[i])
+		}
+	}
+}
+
+func TestTemplateStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"plain $ {x}"`, token.STRING, "plain $ {x}"},
+		{`"hi ${name}!"`, token.TEMPLATE, "hi ${name}!"},
+		{`"${ {"a": "}"}["a"] }"`, token.TEMPLATE, `${ {"a": "}"}["a"] }`},
+		{`"a ${"b`, token.TEMPLATE, `a ${"b`},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	parts, ok := SplitTemplate("a${x + 1}b${ {} }")
+	if !ok {
+		t.Fatalf("SplitTemplate reported an unclosed ${")
+	}
+
+	expected := []TemplatePart{
+		{Text: "a", Offset: 0},
+		{Text: "x + 1", Expr: true, Offset: 3},
+		{Text: "b", Offset: 9},
+		{Text: " {} ", Expr: true, Offset: 12},
+		{Text: "", Offset: 17},
+	}
+	if len(parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d (%+v)", len(expected), len(parts), parts)
+	}
+	for i, part := range expected {
+		if parts[i] != part {
+			t.Errorf("parts[%d] wrong. expected=%+v, got=%+v", i, part, parts[i])
+		}
+	}
+
+	if _, ok := SplitTemplate("a${x"); ok {
+		t.Errorf("SplitTemplate accepted an unclosed ${")
+	}
+}
+
+func TestNewAt(t *testing.T) {
+	l := NewAt("x\n y", 3, 7)
+	for _, expected := range []struct{ line, column int }{{3, 7}, {4, 2}} {
+		tok := l.NextToken()
+		if tok.Line != expected.line || tok.Column != expected.column {
+			t.Errorf("position of %q wrong. expected=%d:%d, got=%d:%d", tok.Literal, expected.line, expected.column, tok.Line, tok.Column)
+		}
+	}
+}
@@ -1,19 +1,67 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
 
 	"github.com/amirhesham65/zzz-lang/repl"
+	"github.com/amirhesham65/zzz-lang/zzz"
 )
 
+// commands are the subcommands of the zzz binary. Without one, zzz runs the
+// file given as its argument, or the REPL.
+var commands = map[string]func(args []string) int{
+	"ast":    astCommand,
+	"build":  build,
+	"check":  check,
+	"disasm": disasm,
+	"fmt":    fmtCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("zzz", flag.ExitOnError)
+	engineName := flags.String("engine", "eval", "execution engine: eval (tree-walking) or vm (bytecode)")
+	optimize := flags.Bool("optimize", true, "fold constants and drop dead branches before running")
+	seed := flags.Int64("seed", 0, "seed for std/random, to make runs reproducible")
+	flags.Parse(args)
+
+	engine, err := zzz.ParseEngine(*engineName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	options := []zzz.Option{zzz.WithEngine(engine), zzz.WithOptimize(*optimize)}
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			options = append(options, zzz.WithSeed(*seed))
+		}
+	})
+	if flags.NArg() > 0 {
+		if _, err := zzz.New(options...).RunFile(flags.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
 	currUser, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
 	fmt.Println("HERA LANG V0 - REPL")
-	repl.Start(currUser.Username, os.Stdin, os.Stdout)
+	repl.Start(currUser.Username, os.Stdin, os.Stdout, options...)
+	return 0
 }
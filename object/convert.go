@@ -0,0 +1,516 @@
+package object
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// tagName is the struct tag consulted when converting structs to and from hashes.
+// `zzz:"name"` renames a field and `zzz:"-"` skips it.
+const tagName = "zzz"
+
+var (
+	objectType  = reflect.TypeOf((*Object)(nil)).Elem()
+	runtimeType = reflect.TypeOf((*Runtime)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// FromGo converts a Go value to its ZZZ counterpart. Integers become INTEGER,
+// floats FLOAT, slices and arrays ARRAY, maps and structs HASH, nil and nil
+// pointers NULL and functions BUILTIN (see WrapFunc). Values that already are
+// Objects are returned unchanged. A value that contains itself, through a
+// pointer, slice or map, is an error.
+func FromGo(v any) (Object, error) {
+	if obj, ok := v.(Object); ok {
+		return obj, nil
+	}
+	return fromValue(reflect.ValueOf(v), map[reference]bool{})
+}
+
+// reference identifies a pointer, slice or map being converted. The type and
+// length tell apart a struct and its first field, or a slice and a shorter
+// slice of the same array.
+type reference struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// fromValue converts v. seen holds the references on the way from the value
+// FromGo was given down to v, which v must not lead back to.
+func fromValue(v reflect.Value, seen map[reference]bool) (Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		if !v.IsNil() {
+			ref := reference{ptr: v.Pointer(), typ: v.Type()}
+			if v.Kind() == reflect.Slice {
+				ref.len = v.Len()
+			}
+			if seen[ref] {
+				return nil, fmt.Errorf("cannot convert Go %s that contains itself", v.Type())
+			}
+			seen[ref] = true
+			defer delete(seen, ref)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", v.Uint())
+		}
+		return &Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+	case reflect.Bool:
+		return NativeBool(v.Bool()), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return fromValue(v.Elem(), seen)
+	case reflect.Slice:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		fallthrough
+	case reflect.Array:
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			el, err := fromValue(v.Index(i), seen)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		// Go maps have no order, sort the keys to build the same hash every time.
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keyLess(keys[i], keys[j])
+		})
+		hash := NewHash(len(keys))
+		for _, k := range keys {
+			key, err := fromValue(k, seen)
+			if err != nil {
+				return nil, fmt.Errorf("map key: %w", err)
+			}
+			value, err := fromValue(v.MapIndex(k), seen)
+			if err != nil {
+				return nil, fmt.Errorf("map value for %s: %w", key.Inspect(), err)
+			}
+			if err := setPair(hash, key, value); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := &Hash{}
+		for _, field := range structFields(v.Type()) {
+			value, err := fromValue(v.FieldByIndex(field.index), seen)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			setPair(hash, &String{Value: field.name}, value)
+		}
+		return hash, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return wrapFunc("", v)
+	default:
+		return nil, fmt.Errorf("cannot convert Go %s to a ZZZ value", v.Type())
+	}
+}
+
+// keyLess orders map keys: integers by value, then strings, then everything
+// else by how it prints. Keys that tie, like int(1) and uint(1) in a
+// map[any]any, are ordered by type.
+func keyLess(a, b reflect.Value) bool {
+	a, b = unwrap(a), unwrap(b)
+	if ra, rb := keyRank(a), keyRank(b); ra != rb {
+		return ra < rb
+	}
+
+	if c := compareKeys(a, b); c != 0 {
+		return c < 0
+	}
+	return a.Type().String() < b.Type().String()
+}
+
+// unwrap returns the value held by the interface v.
+func unwrap(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// keyRank groups map keys: integers first, then strings, then the rest.
+func keyRank(v reflect.Value) int {
+	switch {
+	case isInt(v), isUint(v):
+		return 0
+	case v.Kind() == reflect.String:
+		return 1
+	}
+	return 2
+}
+
+// compareKeys compares two keys of the same rank, returning -1, 0 or 1.
+func compareKeys(a, b reflect.Value) int {
+	switch {
+	case isInt(a) && isInt(b):
+		return cmp.Compare(a.Int(), b.Int())
+	case isUint(a) && isUint(b):
+		return cmp.Compare(a.Uint(), b.Uint())
+	case isInt(a) && isUint(b):
+		if a.Int() < 0 {
+			return -1
+		}
+		return cmp.Compare(uint64(a.Int()), b.Uint())
+	case isUint(a) && isInt(b):
+		return -compareKeys(b, a)
+	case a.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// ToGo converts obj to a Go value of type typ. When typ is an interface type
+// such as any, the natural Go representation is chosen: int64, float64,
+// string, bool, nil, []any and map[any]any.
+func ToGo(obj Object, typ reflect.Type) (any, error) {
+	v, err := toValue(obj, typ)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func toValue(obj Object, typ reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = NULL
+	}
+	isAny := typ.Kind() == reflect.Interface && typ.NumMethod() == 0
+	if !isAny && reflect.TypeOf(obj).AssignableTo(typ) {
+		return reflect.ValueOf(obj), nil
+	}
+	if _, isNull := obj.(*Null); isNull {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(typ), nil
+		}
+	}
+
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to Go %s", obj.Type(), typ)
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		if !isAny {
+			return mismatch()
+		}
+		natural, err := toValue(obj, naturalType(obj))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(typ).Elem()
+		v.Set(natural)
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		v := reflect.New(typ).Elem()
+		if v.OverflowInt(i.Value) {
+			return reflect.Value{}, fmt.Errorf("%d overflows Go %s", i.Value, typ)
+		}
+		v.SetInt(i.Value)
+		return v, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*Integer)
+		if !ok {
+			return mismatch()
+		}
+		v := reflect.New(typ).Elem()
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return reflect.Value{}, fmt.Errorf("%d overflows Go %s", i.Value, typ)
+		}
+		v.SetUint(uint64(i.Value))
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		v := reflect.New(typ).Elem()
+		switch num := obj.(type) {
+		case *Integer:
+			v.SetFloat(float64(num.Value))
+		case *Float:
+			v.SetFloat(num.Value)
+		default:
+			return mismatch()
+		}
+		return v, nil
+	case reflect.String:
+		s, ok := obj.(*String)
+		if !ok {
+			return mismatch()
+		}
+		return reflect.ValueOf(s.Value).Convert(typ), nil
+	case reflect.Bool:
+		b, ok := obj.(*Boolean)
+		if !ok {
+			return mismatch()
+		}
+		return reflect.ValueOf(b.Value).Convert(typ), nil
+	case reflect.Pointer:
+		elem, err := toValue(obj, typ.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Slice, reflect.Array:
+		arr, ok := obj.(*Array)
+		if !ok {
+			return mismatch()
+		}
+		var v reflect.Value
+		if typ.Kind() == reflect.Slice {
+			v = reflect.MakeSlice(typ, len(arr.Elements), len(arr.Elements))
+		} else {
+			if typ.Len() != len(arr.Elements) {
+				return reflect.Value{}, fmt.Errorf("cannot convert ARRAY of length %d to Go %s", len(arr.Elements), typ)
+			}
+			v = reflect.New(typ).Elem()
+		}
+		for i, el := range arr.Elements {
+			elem, err := toValue(el, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			v.Index(i).Set(elem)
+		}
+		return v, nil
+	case reflect.Map:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		v := reflect.MakeMapWithSize(typ, hash.Len())
+		for _, pair := range hash.Pairs() {
+			key, err := toValue(pair.Key, typ.Key())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("hash key: %w", err)
+			}
+			// Frozen arrays become slices, which cannot key a Go map.
+			if !key.Comparable() {
+				return reflect.Value{}, fmt.Errorf("hash key %s cannot key a Go %s", pair.Key.Inspect(), typ)
+			}
+			value, err := toValue(pair.Value, typ.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("hash value for %s: %w", pair.Key.Inspect(), err)
+			}
+			v.SetMapIndex(key, value)
+		}
+		return v, nil
+	case reflect.Struct:
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return mismatch()
+		}
+		v := reflect.New(typ).Elem()
+		for _, field := range structFields(typ) {
+			pair, ok := hash.Get(&String{Value: field.name})
+			if !ok {
+				continue
+			}
+			value, err := toValue(pair.Value, field.typ)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", field.name, err)
+			}
+			v.FieldByIndex(field.index).Set(value)
+		}
+		return v, nil
+	default:
+		return mismatch()
+	}
+}
+
+// naturalType is the Go type an object converts to when the target is any.
+func naturalType(obj Object) reflect.Type {
+	switch obj.(type) {
+	case *Integer:
+		return reflect.TypeOf(int64(0))
+	case *Float:
+		return reflect.TypeOf(float64(0))
+	case *String:
+		return reflect.TypeOf("")
+	case *Boolean:
+		return reflect.TypeOf(false)
+	case *Array:
+		return reflect.TypeOf([]any(nil))
+	case *Hash:
+		return reflect.TypeOf(map[any]any(nil))
+	default:
+		return objectType
+	}
+}
+
+type structField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+}
+
+// structFields lists the exported fields of typ under their ZZZ names.
+func structFields(typ reflect.Type) []structField {
+	var fields []structField
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup(tagName); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, structField{name: name, index: f.Index, typ: f.Type})
+	}
+	return fields
+}
+
+func setPair(hash *Hash, key, value Object) error {
+	hashable, ok := AsKey(key)
+	if !ok {
+		return fmt.Errorf("unusable as hash key: %s", key.Type())
+	}
+	hash.Set(hashable, value)
+	return nil
+}
+
+// WrapFunc turns an arbitrary Go function into a builtin. Arguments are
+// converted with ToGo and results with FromGo; the arity is checked on every
+// call. A leading object.Runtime parameter receives the calling evaluation, and
+// a trailing error result that is non-nil is reported as an ERROR object. name
+// is only used in error messages.
+func WrapFunc(name string, fn any) (*Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("cannot wrap %T as a builtin: not a function", fn)
+	}
+	return wrapFunc(name, v)
+}
+
+func wrapFunc(name string, fn reflect.Value) (*Builtin, error) {
+	typ := fn.Type()
+	if name == "" {
+		name = "builtin"
+	}
+
+	wantsRuntime := typ.NumIn() > 0 && typ.In(0) == runtimeType
+	first := 0
+	if wantsRuntime {
+		first = 1
+	}
+
+	returnsError := typ.NumOut() > 0 && typ.Out(typ.NumOut()-1) == errorType
+	values := typ.NumOut()
+	if returnsError {
+		values--
+	}
+	if values > 1 {
+		return nil, fmt.Errorf("cannot wrap %s as a builtin: too many results", typ)
+	}
+
+	params := typ.NumIn() - first
+	return &Builtin{Fn: func(rt Runtime, args ...Object) Object {
+		if typ.IsVariadic() {
+			if len(args) < params-1 {
+				return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want at least %d", len(args), params-1)}
+			}
+		} else if len(args) != params {
+			return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), params)}
+		}
+
+		in := make([]reflect.Value, 0, first+len(args))
+		if wantsRuntime {
+			in = append(in, reflect.ValueOf(&rt).Elem())
+		}
+		for i, arg := range args {
+			var paramType reflect.Type
+			if typ.IsVariadic() && i >= params-1 {
+				paramType = typ.In(typ.NumIn() - 1).Elem()
+			} else {
+				paramType = typ.In(first + i)
+			}
+			v, err := toValue(arg, paramType)
+			if err != nil {
+				return &Error{Message: fmt.Sprintf("argument %d to `%s`: %s", i+1, name, err)}
+			}
+			in = append(in, v)
+		}
+
+		out := fn.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &Error{Message: err.Error()}
+			}
+		}
+		if values == 0 {
+			return NULL
+		}
+
+		obj, err := fromValue(out[0], map[reference]bool{})
+		if err != nil {
+			return &Error{Message: fmt.Sprintf("result of `%s`: %s", name, err)}
+		}
+		return obj
+	}}, nil
+}
@@ -0,0 +1,245 @@
+package object
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type person struct {
+	Name    string `zzz:"name"`
+	Age     int    `zzz:"age"`
+	Secret  string `zzz:"-"`
+	Email   string
+	private int
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{2.5, "2.5"},
+		{float32(2), "2.0"},
+		{"zzz", "zzz"},
+		{true, "yea"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{(*int)(nil), "null"},
+		{[]any{1, "two", nil}, "[1, two, null]"},
+		{map[string]int{"one": 1}, "{one: 1}"},
+		{&Integer{Value: 5}, "5"},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. got=%q, expected=%q", tt.input, obj.Inspect(), tt.expected)
+		}
+	}
+
+	if obj, _ := FromGo(true); obj != TRUE {
+		t.Errorf("FromGo(true) is not the TRUE singleton")
+	}
+	if _, err := FromGo(uint64(1) << 63); err == nil {
+		t.Errorf("expected overflow error for large uint64")
+	}
+	if _, err := FromGo(make(chan int)); err == nil {
+		t.Errorf("expected error converting a channel")
+	}
+}
+
+func TestFromGoSortsMapKeys(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{map[int]string{10: "ten", 9: "nine", -1: "minus one", 100: "hundred"}, "{-1: minus one, 9: nine, 10: ten, 100: hundred}"},
+		{map[uint8]int{20: 1, 3: 2}, "{3: 2, 20: 1}"},
+		{map[any]int{"b": 1, 10: 2, "a": 3, uint(2): 4, -5: 5}, "{-5: 5, 2: 4, 10: 2, a: 3, b: 1}"},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+		if obj.Inspect() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. got=%q, expected=%q", tt.input, obj.Inspect(), tt.expected)
+		}
+	}
+}
+
+type node struct {
+	Value int
+	Next  *node
+}
+
+func TestFromGoCycles(t *testing.T) {
+	loop := &node{Value: 1}
+	loop.Next = &node{Value: 2, Next: loop}
+	slice := []any{1, nil}
+	slice[1] = slice
+	m := map[string]any{}
+	m["self"] = m
+
+	for _, input := range []any{loop, slice, m} {
+		if _, err := FromGo(input); err == nil || !strings.Contains(err.Error(), "contains itself") {
+			t.Errorf("FromGo(%T) expected a cycle error. got=%v", input, err)
+		}
+	}
+
+	// The same value twice is not a cycle.
+	shared := &node{Value: 3}
+	obj, err := FromGo([]*node{shared, shared})
+	if err != nil {
+		t.Fatalf("FromGo returned error for a shared pointer: %s", err)
+	}
+	if expected := "[{Value: 3, Next: null}, {Value: 3, Next: null}]"; obj.Inspect() != expected {
+		t.Errorf("wrong result. got=%q, expected=%q", obj.Inspect(), expected)
+	}
+}
+
+func TestFromGoStruct(t *testing.T) {
+	obj, err := FromGo(person{Name: "Amir", Age: 25, Secret: "shh", Email: "a@b.c"})
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+
+	hash, ok := obj.(*Hash)
+	if !ok {
+		t.Fatalf("object is not Hash. got=%T", obj)
+	}
+	if hash.Len() != 3 {
+		t.Fatalf("hash has wrong number of pairs. got=%d (%s)", hash.Len(), hash.Inspect())
+	}
+
+	expected := map[string]string{"name": "Amir", "age": "25", "Email": "a@b.c"}
+	for key, value := range expected {
+		pair, ok := hash.Get(&String{Value: key})
+		if !ok {
+			t.Errorf("no pair for key %q", key)
+			continue
+		}
+		if pair.Value.Inspect() != value {
+			t.Errorf("wrong value for %q. got=%q", key, pair.Value.Inspect())
+		}
+	}
+}
+
+func TestToGo(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+	hash, _ := FromGo(map[string]any{"name": "Amir", "age": 25})
+
+	tests := []struct {
+		input    Object
+		typ      reflect.Type
+		expected any
+	}{
+		{&Integer{Value: 5}, reflect.TypeOf(0), 5},
+		{&Integer{Value: 5}, reflect.TypeOf(uint16(0)), uint16(5)},
+		{&Integer{Value: 5}, reflect.TypeOf(0.0), 5.0},
+		{&Float{Value: 1.5}, reflect.TypeOf(float32(0)), float32(1.5)},
+		{&String{Value: "hi"}, reflect.TypeOf(""), "hi"},
+		{TRUE, reflect.TypeOf(false), true},
+		{arr, reflect.TypeOf([]int64{}), []int64{1, 2}},
+		{arr, reflect.TypeOf([2]int{}), [2]int{1, 2}},
+		{arr, reflect.TypeOf((*any)(nil)).Elem(), []any{int64(1), int64(2)}},
+		{NULL, reflect.TypeOf((*int)(nil)), (*int)(nil)},
+		{NULL, reflect.TypeOf((*any)(nil)).Elem(), nil},
+		{hash, reflect.TypeOf(map[string]any{}), map[string]any{"name": "Amir", "age": int64(25)}},
+		{hash, reflect.TypeOf(person{}), person{Name: "Amir", Age: 25}},
+		{arr, reflect.TypeOf((*Object)(nil)).Elem(), arr},
+	}
+
+	for _, tt := range tests {
+		got, err := ToGo(tt.input, tt.typ)
+		if err != nil {
+			t.Errorf("ToGo(%s, %s) returned error: %s", tt.input.Inspect(), tt.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ToGo(%s, %s) wrong. got=%#v, expected=%#v", tt.input.Inspect(), tt.typ, got, tt.expected)
+		}
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	frozenKeys := NewHash(1)
+	setPair(frozenKeys, &Array{Elements: []Object{&Integer{Value: 1}}, Frozen: true}, &Integer{Value: 2})
+
+	tests := []struct {
+		input Object
+		typ   reflect.Type
+	}{
+		{&String{Value: "5"}, reflect.TypeOf(0)},
+		{&Integer{Value: 300}, reflect.TypeOf(int8(0))},
+		{&Integer{Value: -1}, reflect.TypeOf(uint(0))},
+		{&Float{Value: 1.5}, reflect.TypeOf(0)},
+		{&Array{Elements: []Object{&String{Value: "x"}}}, reflect.TypeOf([]int{})},
+		{NULL, reflect.TypeOf(0)},
+		{frozenKeys, reflect.TypeOf(map[any]int{})},
+	}
+
+	for _, tt := range tests {
+		if _, err := ToGo(tt.input, tt.typ); err == nil {
+			t.Errorf("ToGo(%s, %s) expected error", tt.input.Inspect(), tt.typ)
+		}
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	add, err := WrapFunc("add", func(a, b int) int { return a + b })
+	if err != nil {
+		t.Fatalf("WrapFunc returned error: %s", err)
+	}
+	divide, _ := WrapFunc("divide", func(a, b float64) (float64, error) {
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	})
+	sum, _ := WrapFunc("sum", func(nums ...int) int {
+		total := 0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	})
+	noop, _ := WrapFunc("noop", func() {})
+
+	tests := []struct {
+		fn       *Builtin
+		args     []Object
+		expected string
+	}{
+		{add, []Object{&Integer{Value: 1}, &Integer{Value: 2}}, "3"},
+		{add, []Object{&Integer{Value: 1}}, "ERROR: wrong number of arguments. got=1, want=2"},
+		{add, []Object{&Integer{Value: 1}, &String{Value: "2"}}, "ERROR: argument 2 to `add`: cannot convert STRING to Go int"},
+		{divide, []Object{&Integer{Value: 1}, &Integer{Value: 4}}, "0.25"},
+		{divide, []Object{&Integer{Value: 1}, &Integer{Value: 0}}, "ERROR: division by zero"},
+		{sum, []Object{}, "0"},
+		{sum, []Object{&Integer{Value: 1}, &Integer{Value: 2}, &Integer{Value: 3}}, "6"},
+		{noop, []Object{}, "null"},
+	}
+
+	for _, tt := range tests {
+		got := tt.fn.Fn(nil, tt.args...)
+		if got.Inspect() != tt.expected {
+			t.Errorf("wrong result. got=%q, expected=%q", got.Inspect(), tt.expected)
+		}
+	}
+
+	if _, err := WrapFunc("bad", 42); err == nil {
+		t.Errorf("expected error wrapping a non-function")
+	}
+}
@@ -1,7 +1,14 @@
 package object
 
+// Environment holds variable bindings. The global environment of a program, a
+// module or a REPL session is a map, so bindings can be added incrementally.
+// The environment of a function call is a frame: a slice of slots, one per
+// parameter and local binding, that identifiers annotated by the evaluator's
+// resolver index directly.
 type Environment struct {
 	store map[string]Object
+	slots []Object
+	names []string // the name of each slot
 	outer *Environment
 }
 
@@ -10,21 +17,67 @@ func NewEnvironment() *Environment {
 	return &Environment{store: s, outer: nil}
 }
 
+// NewEnclosedEnvironment creates a map-backed environment nested in outer.
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
 	return env
 }
 
+// NewFrame creates a frame nested in outer with one empty slot per name.
+func NewFrame(outer *Environment, names []string) *Environment {
+	return &Environment{slots: make([]Object, len(names)), names: names, outer: outer}
+}
+
+// Get looks name up by name, from e outwards. Slots that are not set yet do
+// not count as bindings.
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	for env := e; env != nil; env = env.outer {
+		for i, slotName := range env.names {
+			if slotName == name && env.slots[i] != nil {
+				return env.slots[i], true
+			}
+		}
+		if obj, ok := env.store[name]; ok {
+			return obj, true
+		}
 	}
-	return obj, ok
+	return nil, false
 }
 
+// Set binds name in e, in its slot if e is a frame that has one.
 func (e *Environment) Set(name string, val Object) Object {
+	for i, slotName := range e.names {
+		if slotName == name {
+			e.slots[i] = val
+			return val
+		}
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// Lookup returns the slot at index slot of the frame depth levels out from e.
+// An unset slot falls back to looking name up in that frame and beyond, which
+// finds the binding the name had before its slot was assigned.
+func (e *Environment) Lookup(depth, slot int, name string) (Object, bool) {
+	frame := e
+	for ; depth > 0; depth-- {
+		frame = frame.outer
+	}
+
+	if obj := frame.slots[slot]; obj != nil {
+		return obj, true
+	}
+	return frame.Get(name)
+}
+
+// SetSlot assigns the slot at index slot of frame e.
+func (e *Environment) SetSlot(slot int, val Object) Object {
+	e.slots[slot] = val
+	return val
+}
@@ -2,18 +2,44 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"io"
+	"math/rand"
+	"strconv"
 	"strings"
 
 	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/code"
 )
 
 type ObjectType string
-type BuiltinFunction func(args ...Object) Object
+type BuiltinFunction func(rt Runtime, args ...Object) Object
+
+// Runtime gives builtin functions access to the evaluation that called them.
+type Runtime interface {
+	Stdout() io.Writer
+	Stderr() io.Writer
+	Stdin() io.Reader
+
+	// Call calls fn, a function or builtin, with args under the same budgets
+	// as the caller. It returns NULL for a function that returns nothing.
+	Call(fn Object, args ...Object) Object
+
+	// Random returns the generator random numbers are drawn from, which lives
+	// as long as the options the evaluation was started with.
+	Random() *rand.Rand
+
+	// Reserve returns the memory limit error if n more bytes would not fit in
+	// the budget, so builtins can refuse a large result before building it.
+	// The result is charged as usual once it is returned.
+	Reserve(n int64) *Error
+}
 
 const (
 	INTEGER_OBJ      ObjectType = "INTEGER"
+	FLOAT_OBJ        ObjectType = "FLOAT"
 	BOOLEAN_OBJ      ObjectType = "BOOLEAN"
 	NULL_OBJ         ObjectType = "NULL"
 	RETURN_VALUE_OBJ ObjectType = "RETURN_VALUE"
@@ -23,6 +49,9 @@ const (
 	BUILTIN_OBJ      ObjectType = "BUILTIN"
 	ARRAY_OBJ        ObjectType = "ARRAY"
 	HASH_OBJ         ObjectType = "HASH"
+	MODULE_OBJ       ObjectType = "MODULE"
+
+	COMPILED_FUNCTION_OBJ ObjectType = "COMPILED_FUNCTION"
 )
 
 type Object interface {
@@ -37,6 +66,19 @@ type Integer struct {
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -50,6 +92,22 @@ func (b *Boolean) Inspect() string {
 	}
 }
 
+// The evaluator compares against these singletons by identity, so host code
+// should use them (or NativeBool) rather than allocating its own.
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// NativeBool returns the Boolean singleton for b.
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
 type Null struct{}
 
 func (b *Null) Type() ObjectType { return NULL_OBJ }
@@ -62,8 +120,31 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+type ErrorKind string
+
+const (
+	STEP_LIMIT_ERR   ErrorKind = "STEP_LIMIT"
+	MEMORY_LIMIT_ERR ErrorKind = "MEMORY_LIMIT"
+	DEPTH_LIMIT_ERR  ErrorKind = "DEPTH_LIMIT"
+	CANCELED_ERR     ErrorKind = "CANCELED"
+
+	// CONVERSION_ERR marks a value that could not be converted, as by
+	// int("abc").
+	CONVERSION_ERR ErrorKind = "CONVERSION"
+)
+
 type Error struct {
 	Message string
+	Kind    ErrorKind // empty for ordinary runtime errors
+}
+
+// IsLimit reports whether the error was raised because an evaluation budget ran out.
+func (e *Error) IsLimit() bool {
+	switch e.Kind {
+	case STEP_LIMIT_ERR, MEMORY_LIMIT_ERR, DEPTH_LIMIT_ERR, CANCELED_ERR:
+		return true
+	}
+	return false
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -72,15 +153,22 @@ func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
+	Locals     []string // slot names of the call frame, nil if the body is unresolved
 	Env        *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
+	return FunctionSource(f.Parameters, f.Body)
+}
+
+// FunctionSource renders a function literal with the given parameters and
+// body, which is how both engines print functions.
+func FunctionSource(parameters []*ast.Identifier, body *ast.BlockStatement) string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, p := range f.Parameters {
+	for _, p := range parameters {
 		params = append(params, p.String())
 	}
 
@@ -88,7 +176,7 @@ func (f *Function) Inspect() string {
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
+	out.WriteString(body.String())
 	out.WriteString("\n}")
 
 	return out.String()
@@ -110,6 +198,7 @@ func (b *Builtin) Inspect() string  { return "builtin function" }
 
 type Array struct {
 	Elements []Object
+	Frozen   bool // set by freeze; a frozen array never changes and can be a hash key
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -128,6 +217,8 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// HashKey is a digest of a hash key. Different keys may have the same digest,
+// so hashes compare keys with Equal as well.
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -153,25 +244,155 @@ func (s *String) HashKey() HashKey {
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
 
+// HashKey combines the keys of the elements of a frozen array.
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, el := range a.Elements {
+		key := el.(Hashable).HashKey()
+		h.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf[:], key.Value)
+		h.Write(buf[:])
+	}
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+func (b *Boolean) Equal(other Object) bool {
+	o, ok := other.(*Boolean)
+	return ok && o.Value == b.Value
+}
+
+func (i *Integer) Equal(other Object) bool {
+	o, ok := other.(*Integer)
+	return ok && o.Value == i.Value
+}
+
+func (s *String) Equal(other Object) bool {
+	o, ok := other.(*String)
+	return ok && o.Value == s.Value
+}
+
+func (a *Array) Equal(other Object) bool {
+	o, ok := other.(*Array)
+	if !ok || len(o.Elements) != len(a.Elements) {
+		return false
+	}
+	for i, el := range a.Elements {
+		if !el.(Hashable).Equal(o.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 type HashPair struct {
 	Key   Object
 	Value Object
 }
 
+// Hashable is implemented by objects that can be hash keys. Equal reports
+// whether other is the same key, for keys whose HashKey collides.
 type Hashable interface {
+	Object
 	HashKey() HashKey
+	Equal(other Object) bool
 }
 
+// AsKey returns obj as a hash key, if it can be one. Arrays can only be keys
+// once frozen.
+func AsKey(obj Object) (Hashable, bool) {
+	if arr, ok := obj.(*Array); ok && !arr.Frozen {
+		return nil, false
+	}
+	key, ok := obj.(Hashable)
+	return key, ok
+}
+
+// Hash maps keys to values and remembers the order keys were first set in,
+// which is the order Pairs returns them and Inspect prints them. The zero
+// value is an empty hash.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	index map[HashKey][]int // positions in pairs of the keys with each digest
+	pairs []HashPair
+}
+
+// NewHash returns an empty hash with room for size pairs.
+func NewHash(size int) *Hash {
+	return &Hash{index: make(map[HashKey][]int, size), pairs: make([]HashPair, 0, size)}
+}
+
+// find returns the position of key in pairs, or -1.
+func (h *Hash) find(key Hashable) int {
+	for _, i := range h.index[key.HashKey()] {
+		if key.Equal(h.pairs[i].Key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the pair stored under key.
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+	if i := h.find(key); i >= 0 {
+		return h.pairs[i], true
+	}
+	return HashPair{}, false
 }
 
+// Set stores value under key. A new key goes last; an existing one keeps its
+// place.
+func (h *Hash) Set(key Hashable, value Object) {
+	if i := h.find(key); i >= 0 {
+		h.pairs[i] = HashPair{Key: key, Value: value}
+		return
+	}
+	if h.index == nil {
+		h.index = make(map[HashKey][]int)
+	}
+	digest := key.HashKey()
+	h.index[digest] = append(h.index[digest], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Delete removes the pair stored under key and reports whether there was one.
+func (h *Hash) Delete(key Hashable) bool {
+	i := h.find(key)
+	if i < 0 {
+		return false
+	}
+	h.pairs = append(h.pairs[:i], h.pairs[i+1:]...)
+	for digest, positions := range h.index {
+		kept := positions[:0]
+		for _, j := range positions {
+			switch {
+			case j > i:
+				kept = append(kept, j-1)
+			case j < i:
+				kept = append(kept, j)
+			}
+		}
+		if len(kept) == 0 {
+			delete(h.index, digest)
+		} else {
+			h.index[digest] = kept
+		}
+	}
+	return true
+}
+
+// Len returns the number of pairs in the hash.
+func (h *Hash) Len() int { return len(h.pairs) }
+
+// Pairs returns the pairs of the hash in insertion order. The slice belongs to
+// the hash and must not be modified.
+func (h *Hash) Pairs() []HashPair { return h.pairs }
+
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.pairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -181,3 +402,51 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+type Module struct {
+	Name string
+	Path string       // resolved file path, or the "std/..." name of a standard module
+	Env  *Environment // the module's top-level bindings are its exports
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "<module " + m.Name + ">" }
+
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	Lines         code.LineTable // debug line table of Instructions
+	NumLocals     int
+	NumParameters int
+	Name          string // the binding the function literal was assigned to, if any
+	Source        string // the function literal, as FunctionSource renders it
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Unit is the runtime state shared by the closures of one compiled program or
+// module: the constant pool their instructions refer to and the global variables.
+type Unit struct {
+	Constants []Object
+	Globals   []Object
+	Names     []string // Names[i] is the binding stored in Globals[i]
+}
+
+// Closure is a compiled function paired with the free variables it captured and
+// the unit it was compiled in. It reports FUNCTION_OBJ so that both engines
+// describe functions the same way.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object // the cells of the captured locals, shared with the frames that own them
+	Unit *Unit
+}
+
+func (c *Closure) Type() ObjectType { return FUNCTION_OBJ }
+func (c *Closure) Inspect() string {
+	if c.Fn.Source == "" {
+		return fmt.Sprintf("Closure[%p]", c)
+	}
+	return c.Fn.Source
+}
@@ -17,3 +17,108 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	str := func(value string) *String { return &String{Value: value} }
+
+	hash := &Hash{}
+	for _, key := range []string{"c", "a", "b"} {
+		hash.Set(str(key), &Integer{Value: 1})
+	}
+	if hash.Inspect() != "{c: 1, a: 1, b: 1}" {
+		t.Errorf("wrong order. got=%s", hash.Inspect())
+	}
+
+	hash.Set(str("a"), &Integer{Value: 2})
+	if hash.Inspect() != "{c: 1, a: 2, b: 1}" {
+		t.Errorf("replacing a value moved its key. got=%s", hash.Inspect())
+	}
+
+	if !hash.Delete(str("c")) {
+		t.Errorf("Delete did not find c")
+	}
+	if hash.Delete(str("c")) {
+		t.Errorf("Delete found c twice")
+	}
+	if hash.Inspect() != "{a: 2, b: 1}" || hash.Len() != 2 {
+		t.Errorf("wrong hash after Delete. got=%s", hash.Inspect())
+	}
+	if pair, ok := hash.Get(str("b")); !ok || pair.Value.Inspect() != "1" {
+		t.Errorf("Get after Delete returned %v, %t", pair, ok)
+	}
+
+	hash.Set(str("a"), &Integer{Value: 3})
+	hash.Set(str("c"), &Integer{Value: 4})
+	if hash.Inspect() != "{a: 3, b: 1, c: 4}" {
+		t.Errorf("wrong order after re-adding c. got=%s", hash.Inspect())
+	}
+}
+
+// collider is a hash key whose digest is the same for every value.
+type collider struct{ String }
+
+func (c *collider) HashKey() HashKey { return HashKey{Type: STRING_OBJ, Value: 42} }
+func (c *collider) Equal(other Object) bool {
+	o, ok := other.(*collider)
+	return ok && o.Value == c.Value
+}
+
+func TestHashCollidingKeysStayDistinct(t *testing.T) {
+	a, b, c := &collider{String{Value: "a"}}, &collider{String{Value: "b"}}, &collider{String{Value: "c"}}
+	if a.HashKey() != b.HashKey() {
+		t.Fatal("expected colliding digests")
+	}
+
+	hash := &Hash{}
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+	hash.Set(c, &Integer{Value: 3})
+	hash.Set(&collider{String{Value: "b"}}, &Integer{Value: 20})
+
+	if hash.Len() != 3 {
+		t.Fatalf("expected 3 pairs, got %d: %s", hash.Len(), hash.Inspect())
+	}
+	for key, expected := range map[*collider]string{a: "1", b: "20", c: "3"} {
+		pair, ok := hash.Get(&collider{String{Value: key.Value}})
+		if !ok || pair.Value.Inspect() != expected {
+			t.Errorf("Get(%s) = %v, %t. want %s", key.Value, pair.Value, ok, expected)
+		}
+	}
+
+	hash.Delete(a)
+	if _, ok := hash.Get(a); ok {
+		t.Errorf("a is still present after Delete")
+	}
+	if pair, ok := hash.Get(c); !ok || pair.Value.Inspect() != "3" {
+		t.Errorf("deleting a lost c: %v, %t", pair.Value, ok)
+	}
+}
+
+func TestFrozenArrayHashKey(t *testing.T) {
+	pair := func(x, y int64) *Array {
+		return &Array{Elements: []Object{&Integer{Value: x}, &Integer{Value: y}}, Frozen: true}
+	}
+
+	if pair(1, 2).HashKey() != pair(1, 2).HashKey() {
+		t.Errorf("equal arrays have different hash keys")
+	}
+	if pair(1, 2).HashKey() == pair(2, 1).HashKey() {
+		t.Errorf("different arrays have the same hash key")
+	}
+	if !pair(1, 2).Equal(pair(1, 2)) || pair(1, 2).Equal(pair(1, 3)) {
+		t.Errorf("Equal compares the wrong elements")
+	}
+
+	nested := &Array{Elements: []Object{pair(1, 2), &String{Value: "x"}}, Frozen: true}
+	other := &Array{Elements: []Object{pair(1, 2), &String{Value: "x"}}, Frozen: true}
+	if nested.HashKey() != other.HashKey() || !nested.Equal(other) {
+		t.Errorf("equal nested arrays are different keys")
+	}
+
+	if _, ok := AsKey(&Array{Elements: []Object{&Integer{Value: 1}}}); ok {
+		t.Errorf("an array that is not frozen can be a hash key")
+	}
+	if _, ok := AsKey(pair(1, 2)); !ok {
+		t.Errorf("a frozen array cannot be a hash key")
+	}
+}
@@ -0,0 +1,273 @@
+// Package optimizer rewrites parsed programs into cheaper equivalents before
+// they are evaluated or compiled.
+package optimizer
+
+import (
+	"strconv"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/token"
+)
+
+// Optimize rewrites program in place and returns it. It
+//
+//   - folds operators applied to integer, string and boolean literals, and
+//   - drops `fr` branches whose condition is a literal and can never run.
+//
+// The optimized program produces the same results as the original one. Folds
+// that would fail at runtime, like a division by zero, are left alone so the
+// error is still reported when the expression is evaluated.
+func Optimize(program *ast.Program) *ast.Program {
+	program.Statements = statements(program.Statements)
+	return program
+}
+
+// Literals returns the runtime object of every integer and string literal in
+// program, for evaluator.Options.Literals. The evaluator then hands out these
+// objects instead of allocating new ones each time a literal is evaluated.
+// The table is only read while programs run, so it can be shared.
+func Literals(program *ast.Program) map[ast.Node]object.Object {
+	literals := map[ast.Node]object.Object{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.IntegerLiteral:
+			literals[node] = &object.Integer{Value: node.Value}
+		case *ast.StringLiteral:
+			literals[node] = &object.String{Value: node.Value}
+		}
+		return true
+	})
+	return literals
+}
+
+// statements optimizes a statement list. A `fr` statement with a literal
+// condition is replaced by the statements of the branch it takes, unless it
+// is the last statement and so provides the value of the list.
+func statements(stmts []ast.Statement) []ast.Statement {
+	out := make([]ast.Statement, 0, len(stmts))
+	for i, stmt := range stmts {
+		stmt = statement(stmt)
+
+		if es, ok := stmt.(*ast.ExpressionStatement); ok && i < len(stmts)-1 {
+			if ie, ok := es.Expression.(*ast.IfExpression); ok {
+				if taken, known := branch(ie); known {
+					if taken != nil {
+						out = append(out, taken.Statements...)
+					}
+					continue
+				}
+			}
+		}
+
+		out = append(out, stmt)
+	}
+	return out
+}
+
+func statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = expression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = expression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		stmt.Expression = expression(stmt.Expression)
+	case *ast.BlockStatement:
+		block(stmt)
+	}
+	return stmt
+}
+
+func block(b *ast.BlockStatement) {
+	if b != nil {
+		b.Statements = statements(b.Statements)
+	}
+}
+
+func expression(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case *ast.PrefixExpression:
+		expr.Right = expression(expr.Right)
+		return foldPrefix(expr)
+	case *ast.InfixExpression:
+		expr.Left = expression(expr.Left)
+		expr.Right = expression(expr.Right)
+		return foldInfix(expr)
+	case *ast.IfExpression:
+		expr.Condition = expression(expr.Condition)
+		block(expr.Consequence)
+		block(expr.Alternative)
+		return simplifyIf(expr)
+	case *ast.FunctionLiteral:
+		block(expr.Body)
+	case *ast.CallExpression:
+		expr.Function = expression(expr.Function)
+		for i, arg := range expr.Arguments {
+			expr.Arguments[i] = expression(arg)
+		}
+	case *ast.ArrayLiteral:
+		for i, el := range expr.Elements {
+			expr.Elements[i] = expression(el)
+		}
+	case *ast.TemplateLiteral:
+		for i, el := range expr.Exprs {
+			expr.Exprs[i] = expression(el)
+		}
+	case *ast.IndexExpression:
+		expr.Left = expression(expr.Left)
+		expr.Index = expression(expr.Index)
+	case *ast.SliceExpression:
+		expr.Left = expression(expr.Left)
+		expr.Start = expression(expr.Start)
+		expr.End = expression(expr.End)
+	case *ast.MemberExpression:
+		expr.Object = expression(expr.Object)
+	case *ast.HashLiteral:
+		for i, pair := range expr.Pairs {
+			expr.Pairs[i] = ast.HashPair{Key: expression(pair.Key), Value: expression(pair.Value)}
+		}
+	}
+	return expr
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch node.Operator {
+	case "-":
+		if right, ok := node.Right.(*ast.IntegerLiteral); ok {
+			return integer(node.Token, -right.Value)
+		}
+	case "!":
+		if truthy, ok := literalTruth(node.Right); ok {
+			return boolean(node.Token, !truthy)
+		}
+	}
+	return node
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return node
+		}
+		switch node.Operator {
+		case "+":
+			return integer(node.Token, left.Value+right.Value)
+		case "-":
+			return integer(node.Token, left.Value-right.Value)
+		case "*":
+			return integer(node.Token, left.Value*right.Value)
+		case "/":
+			if right.Value != 0 {
+				return integer(node.Token, left.Value/right.Value)
+			}
+		case "<":
+			return boolean(node.Token, left.Value < right.Value)
+		case ">":
+			return boolean(node.Token, left.Value > right.Value)
+		case "==":
+			return boolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return boolean(node.Token, left.Value != right.Value)
+		}
+
+	case *ast.StringLiteral:
+		right, ok := node.Right.(*ast.StringLiteral)
+		if !ok {
+			return node
+		}
+		switch node.Operator {
+		case "+":
+			return str(node.Token, left.Value+right.Value)
+		case "==":
+			return boolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return boolean(node.Token, left.Value != right.Value)
+		}
+
+	case *ast.Boolean:
+		right, ok := node.Right.(*ast.Boolean)
+		if !ok {
+			return node
+		}
+		switch node.Operator {
+		case "==":
+			return boolean(node.Token, left.Value == right.Value)
+		case "!=":
+			return boolean(node.Token, left.Value != right.Value)
+		}
+	}
+	return node
+}
+
+// simplifyIf reduces a `fr` with a literal condition to the branch it takes:
+// the branch's expression when it is a single one, otherwise a `fr (yea)`
+// around the branch, or an empty `fr` when no branch is taken.
+func simplifyIf(ie *ast.IfExpression) ast.Expression {
+	taken, known := branch(ie)
+	if !known {
+		return ie
+	}
+
+	if taken == nil {
+		ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token}
+		ie.Alternative = nil
+		return ie
+	}
+	if len(taken.Statements) == 1 {
+		if es, ok := taken.Statements[0].(*ast.ExpressionStatement); ok && es.Expression != nil {
+			return es.Expression
+		}
+	}
+
+	ie.Condition = boolean(ie.Token, true)
+	ie.Consequence = taken
+	ie.Alternative = nil
+	return ie
+}
+
+// branch returns the block ie runs, or nil if it runs none, when the
+// condition is a literal.
+func branch(ie *ast.IfExpression) (taken *ast.BlockStatement, known bool) {
+	truthy, ok := literalTruth(ie.Condition)
+	if !ok {
+		return nil, false
+	}
+	if truthy {
+		return ie.Consequence, true
+	}
+	return ie.Alternative, true
+}
+
+// literalTruth reports the truthiness of expr if it is a literal.
+func literalTruth(expr ast.Expression) (truthy bool, ok bool) {
+	switch expr := expr.(type) {
+	case *ast.Boolean:
+		return expr.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+// The folded literals keep the position of the expression they replace.
+
+func integer(at token.Token, value int64) *ast.IntegerLiteral {
+	at.Type, at.Literal = token.INT, strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: at, Value: value}
+}
+
+func str(at token.Token, value string) *ast.StringLiteral {
+	at.Type, at.Literal = token.STRING, value
+	return &ast.StringLiteral{Token: at, Value: value}
+}
+
+func boolean(at token.Token, value bool) *ast.Boolean {
+	at.Type, at.Literal = token.FALSE, "nah"
+	if value {
+		at.Type, at.Literal = token.TRUE, "yea"
+	}
+	return &ast.Boolean{Token: at, Value: value}
+}
@@ -0,0 +1,137 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 2", "3"},
+		{"-(2 + 3)", "-5"},
+		{"1 < 2", "yea"},
+		{"2 * 3 == 6", "yea"},
+		{"3 != 3", "nah"},
+		{`"zzz" + "!"`, "zzz!"},
+		{`"a" == "b"`, "nah"},
+		{"yea == nah", "nah"},
+		{"!yea", "nah"},
+		{"!5", "nah"},
+		{"!!\"s\"", "yea"},
+		{"x + 1 * 2", "(x + 2)"},
+		{"lit y = 60 * 60;", "lit y = 3600;"},
+		{"fun(x) { x * (2 + 2) }", "fun(x) (x * 4)"},
+		{"f(1 + 1, [2 * 2])", "f(2, [4])"},
+		{"a[1 + 1]", "(a[2])"},
+		// Folding these would hide a runtime error or a type mismatch.
+		{"1 / 0", "(1 / 0)"},
+		{"1 + yea", "(1 + yea)"},
+		{`"a" - "b"`, "(a - b)"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+		if got := program.String(); got != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestDeadBranchRemoval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fr (yea) { 1 } lowkey { 2 }", "1"},
+		{"fr (1 > 2) { 1 } lowkey { 2 }", "2"},
+		{"fr (nah) { 1 }", "frnah "},
+		{"fr (5) { lit a = 1; a }", "fryea lit a = 1;a"},
+		{"fr (x) { 1 } lowkey { 2 }", "frx 1lowkey 2"},
+		// Branches that are not the last statement are spliced into the list.
+		{"fr (nah) { f() }; 3", "3"},
+		{"fr (yea) { lit a = 1; } lowkey { lit b = 2; }; a", "lit a = 1;a"},
+		{"fun() { fr (yea) { return 1; }; 2 }", "fun() return 1;2"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+		if got := program.String(); got != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestLiteralsAreCached(t *testing.T) {
+	program := Optimize(parse(t, `lit f = fun() { [7, "seven"] }; f() + 1`))
+
+	var literals []ast.Expression
+	let := program.Statements[0].(*ast.LetStatement)
+	body := let.Value.(*ast.FunctionLiteral).Body.Statements[0].(*ast.ExpressionStatement)
+	literals = append(literals, body.Expression.(*ast.ArrayLiteral).Elements...)
+	literals = append(literals, program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Right)
+
+	cached := Literals(program)
+	for _, literal := range literals {
+		if obj, ok := cached[literal]; !ok || obj.Inspect() != literal.String() {
+			t.Errorf("literal %s has no cached object. got=%v", literal, obj)
+		}
+	}
+}
+
+// TestOptimizedProgramsAgree evaluates programs with and without optimization
+// and requires identical results.
+func TestOptimizedProgramsAgree(t *testing.T) {
+	inputs := []string{
+		"1 + 2 * 3 - 4 / 2",
+		"-(7 * 6) + 100",
+		`"hello" + " " + "world"`,
+		`"zzz" == "z" + "zz"`,
+		"fr (2 > 1) { 10 } lowkey { 20 }",
+		"fr (nah) { 10 }",
+		"fr (yea) { lit a = 5; a * 2 }",
+		"lit a = 1; fr (yea) { lit a = 2; }; a",
+		"lit f = fun(n) { fr (n < 2) { return n; }; f(n - 1) + f(n - 2) }; f(10)",
+		"lit f = fun() { fr (yea) { return 1 + 1; }; 3 }; f()",
+		`{"a" + "b": 1 + 1}["ab"]`,
+		"[1 + 1, 2 * 2][3 - 2]",
+		"10 / (5 - 5)",
+		"1 + yea",
+		"lit x = 5; x == 5",
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		got := evaluator.Eval(Optimize(parse(t, input)), object.NewEnvironment())
+
+		if describe(expected) != describe(got) {
+			t.Errorf("optimized result differs for %q. expected=%s, got=%s", input, describe(expected), describe(got))
+		}
+	}
+}
+
+func describe(obj object.Object) string {
+	if obj == nil {
+		return "<no value>"
+	}
+	return string(obj.Type()) + " " + obj.Inspect()
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
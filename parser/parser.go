@@ -4,6 +4,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/amirhesham65/zzz-lang/ast"
 	"github.com/amirhesham65/zzz-lang/lexer"
@@ -44,6 +45,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE, p.parseTemplateLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
@@ -52,12 +54,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression) // fun(x, y)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	return p
 }
@@ -77,6 +81,7 @@ const (
 	SUM                    // SUM represents the precedence level for addition and subtraction (+, -)
 	PRODUCT                // PRODUCT represents the precedence level for multiplication and division (*, /)
 	PREFIX                 // PREFIX represents the precedence level for prefix operators (!, -)
+	POWER                  // POWER represents the precedence level for exponentiation (**)
 	CALL                   // CALL represents the precedence level for function calls
 	INDEX                  // INDEX represents the precedence level for indexing expressions
 )
@@ -91,8 +96,19 @@ var precedences = map[token.TokenType]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
+}
+
+// Precedence returns the binding power of an infix operator token, or LOWEST
+// for any other token.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
 }
 
 // peakPrecedence returns the precedence of the peek token.
@@ -117,7 +133,8 @@ func (p *Parser) nextToken() {
 	p.peekToken = p.l.NextToken()
 }
 
-// ParseProgram parses a program and returns its AST representation.
+// ParseProgram parses a program and returns its AST representation. A program
+// without errors comes back resolved by ast.Resolve.
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
@@ -129,6 +146,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+
+	if len(p.errors) == 0 {
+		ast.Resolve(program)
+	}
 	return program
 }
 
@@ -139,6 +160,8 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -153,6 +176,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name.Type = p.parseTypeAnnotation(token.COLON)
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -162,7 +186,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -183,6 +207,31 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+// parseImportStatement parses an import statement with an optional alias.
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	stmt.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 // parseExpressionStatement parses an expression statement.
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -243,6 +292,56 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseTemplateLiteral parses a string with embedded expressions. Each
+// expression is parsed on its own, with positions in the enclosing source.
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	tmpl := &ast.TemplateLiteral{Token: p.curToken}
+
+	parts, ok := lexer.SplitTemplate(p.curToken.Literal)
+	if !ok {
+		p.errors = append(p.errors, "unterminated ${ in template string")
+		return nil
+	}
+
+	for _, part := range parts {
+		if !part.Expr {
+			tmpl.Texts = append(tmpl.Texts, part.Text)
+			continue
+		}
+
+		line, column := p.templatePosition(part.Offset)
+		sub := New(lexer.NewAt(part.Text, line, column))
+		if sub.curTokenIs(token.EOF) {
+			p.errors = append(p.errors, "empty ${} in template string")
+			return nil
+		}
+		expr := sub.parseExpression(LOWEST)
+		if len(sub.errors) == 0 && !sub.peekTokenIs(token.EOF) {
+			sub.errors = append(sub.errors, fmt.Sprintf("unexpected %s in template string", sub.peekToken.Type))
+		}
+		if len(sub.errors) > 0 {
+			p.errors = append(p.errors, sub.errors...)
+			return nil
+		}
+		tmpl.Exprs = append(tmpl.Exprs, expr)
+	}
+
+	return tmpl
+}
+
+// templatePosition returns the line and column of the byte at offset in the
+// literal of the current TEMPLATE token, whose opening quote is at the token's
+// position.
+func (p *Parser) templatePosition(offset int) (int, int) {
+	text := p.curToken.Literal[:offset]
+	line, column := p.curToken.Line, p.curToken.Column+1+offset
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		line += strings.Count(text, "\n")
+		column = offset - i
+	}
+	return line, column
+}
+
 // parsePrefixExpression parses a prefix expression.
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
@@ -277,6 +376,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.POWER) {
+		// `**` is right-associative: 2 ** 3 ** 2 is 2 ** (3 ** 2).
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -331,6 +434,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	lit.ReturnType = p.parseTypeAnnotation(token.ARROW)
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -353,12 +457,14 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	p.nextToken()
 
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	ident.Type = p.parseTypeAnnotation(token.COLON)
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		ident.Type = p.parseTypeAnnotation(token.COLON)
 		identifiers = append(identifiers, ident)
 	}
 
@@ -368,10 +474,28 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+// parseTypeAnnotation parses the type name following a separator token, like
+// the `: int` of a binding or the `-> int` of a function, if the next token is
+// that separator. A type name is an identifier, or `fun` for functions.
+func (p *Parser) parseTypeAnnotation(separator token.TokenType) *ast.TypeAnnotation {
+	if !p.peekTokenIs(separator) {
+		return nil
+	}
+	p.nextToken()
+
+	if !p.peekTokenIs(token.IDENT) && !p.peekTokenIs(token.FUNCTION) {
+		p.peakError(token.IDENT)
+		return nil
+	}
+	p.nextToken()
+	return &ast.TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+}
+
 // parseCallExpression parses a call expression.
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.End = p.curToken
 	return exp
 }
 
@@ -411,6 +535,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		block.Statements = append(block.Statements, stmt)
 		p.nextToken()
 	}
+	block.End = p.curToken
 
 	return block
 }
@@ -419,6 +544,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.End = p.curToken
 	return array
 }
 
@@ -447,12 +573,30 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
-// parseIndexExpression parses an index expression.
+// parseIndexExpression parses an index expression, or a slice expression if
+// the brackets hold a colon.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
 
+	var start ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		start = p.parseExpression(LOWEST)
+	}
+
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{Token: tok, Left: left, Index: start}
+	}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
@@ -461,10 +605,22 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMemberExpression parses a member access such as `person.name`.
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
 // parseHashLiteral parses a hash literal.
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashPair{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -477,7 +633,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -487,6 +643,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.End = p.curToken
 	return hash
 }
 
@@ -253,6 +253,26 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a * b ** c * d",
+			"((a * (b ** c)) * d)",
+		},
+		{
+			"a ** -b ** c",
+			"(a ** (-(b ** c)))",
+		},
+		{
+			"a ** b[0] ** f(c)",
+			"(a ** ((b[0]) ** f(c)))",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -353,10 +373,26 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * [1, 2, 3, 4][b * c] * d",
 			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
 		},
+		{
+			"a + b[1 + c:d][0]",
+			"(a + ((b[(1 + c):d])[0]))",
+		},
 		{
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"-a.b * c.d",
+			"((-(a.b)) * (c.d))",
+		},
+		{
+			"a.b.c[0].d",
+			"((((a.b).c)[0]).d)",
+		},
+		{
+			"mod.fn(x.y) + 1",
+			"((mod.fn)((x.y)) + 1)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -727,6 +763,98 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input      string
+		start, end any // nil when the bound is omitted
+	}{
+		{"s[1:3]", 1, 3},
+		{"s[:3]", nil, 3},
+		{"s[1:]", 1, nil},
+		{"s[:]", nil, nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+		if !testIdentifier(t, slice.Left, "s") {
+			return
+		}
+
+		for _, bound := range []struct {
+			got      ast.Expression
+			expected any
+		}{{slice.Start, tt.start}, {slice.End, tt.end}} {
+			if bound.expected == nil {
+				if bound.got != nil {
+					t.Errorf("%q: expected an omitted bound, got %s", tt.input, bound.got)
+				}
+				continue
+			}
+			testLiteralExpression(t, bound.got, bound.expected)
+		}
+	}
+}
+
+func TestParsingTemplateLiterals(t *testing.T) {
+	l := lexer.New(`"hi ${name}, ${1 + 2}"`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	tmpl, ok := stmt.Expression.(*ast.TemplateLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.TemplateLiteral. got=%T", stmt.Expression)
+	}
+
+	expectedTexts := []string{"hi ", ", ", ""}
+	if len(tmpl.Texts) != len(expectedTexts) || len(tmpl.Exprs) != 2 {
+		t.Fatalf("wrong parts. texts=%q, exprs=%d", tmpl.Texts, len(tmpl.Exprs))
+	}
+	for i, text := range expectedTexts {
+		if tmpl.Texts[i] != text {
+			t.Errorf("tmpl.Texts[%d] wrong. expected=%q, got=%q", i, text, tmpl.Texts[i])
+		}
+	}
+	testIdentifier(t, tmpl.Exprs[0], "name")
+	testInfixExpression(t, tmpl.Exprs[1], 1, "+", 2)
+
+	name := tmpl.Exprs[0].(*ast.Identifier)
+	if name.Token.Line != 1 || name.Token.Column != 7 {
+		t.Errorf("wrong position for name. expected=1:7, got=%d:%d", name.Token.Line, name.Token.Column)
+	}
+}
+
+func TestParsingInvalidTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a ${b"`, "unterminated ${ in template string"},
+		{`"a ${ }"`, "empty ${} in template string"},
+		{`"a ${b c}"`, "unexpected IDENT in template string"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %s. got=%q, expected=%q", tt.input, errors, tt.expected)
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "lit" {
 		t.Errorf("s.TokenLiteral not 'lit'. got=%q", s.TokenLiteral())
@@ -799,14 +927,14 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 		}
 
 		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 	}
 }
 
@@ -859,17 +987,17 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 		}
 		testFunc, ok := tests[literal.String()]
 		if !ok {
 			t.Errorf("no test function for key %q found", literal.String())
 			continue
 		}
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
@@ -1000,3 +1128,130 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedAlias string
+	}{
+		{`import "path/to/mod.zzz";`, "path/to/mod.zzz", ""},
+		{`import "std/strings" as s;`, "std/strings", "s"},
+		{`import "helpers"`, "helpers", ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Path.Value != tt.expectedPath {
+			t.Errorf("stmt.Path.Value not %q. got=%q", tt.expectedPath, stmt.Path.Value)
+		}
+
+		alias := ""
+		if stmt.Alias != nil {
+			alias = stmt.Alias.Value
+		}
+		if alias != tt.expectedAlias {
+			t.Errorf("stmt.Alias not %q. got=%q", tt.expectedAlias, alias)
+		}
+	}
+}
+
+func TestLetStatementWithoutSemicolon(t *testing.T) {
+	l := lexer.New("lit x = 5")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 || !testLetStatement(t, program.Statements[0], "x") {
+		t.Fatalf("wrong program. got=%q", program.String())
+	}
+}
+
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "person.name;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	member, ok := stmt.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MemberExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, member.Object, "person") {
+		return
+	}
+	if !testIdentifier(t, member.Property, "name") {
+		return
+	}
+}
+
+func TestParsingInvalidMemberExpression(t *testing.T) {
+	l := lexer.New("person.5")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "expected next token to be of type IDENT, got INT instead" {
+		t.Errorf("wrong parser errors. got=%q", errors)
+	}
+}
+
+func TestParsingTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"lit x: int = 5;", "lit x: int = 5;"},
+		{"lit f = fun(a: int, b) -> int { a };", "lit f = fun(a: int, b) -> int a;"},
+		{"lit g: fun = fun() -> string { \"s\" };", "lit g: fun = fun() -> string s;"},
+		{"fun(x: array) { x }", "fun(x: array) x"},
+		{"5 - -1", "(5 - (-1))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong program. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("fun(a: int) -> int { a }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fn.Parameters[0].Type.Name != "int" || fn.Parameters[0].Type.Token.Column != 8 {
+		t.Errorf("wrong parameter annotation. got=%+v", fn.Parameters[0].Type)
+	}
+	if fn.ReturnType.Name != "int" {
+		t.Errorf("wrong return annotation. got=%+v", fn.ReturnType)
+	}
+}
+
+func TestParsingInvalidTypeAnnotation(t *testing.T) {
+	p := New(lexer.New("lit x: 5 = 5;"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "expected next token to be of type IDENT, got INT instead" {
+		t.Errorf("wrong parser errors. got=%q", errors)
+	}
+}
@@ -2,46 +2,47 @@ package repl
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"strings"
 
-	"github.com/amirhesham65/zzz-lang/evaluator"
-	"github.com/amirhesham65/zzz-lang/lexer"
-	"github.com/amirhesham65/zzz-lang/object"
-	"github.com/amirhesham65/zzz-lang/parser"
+	"github.com/amirhesham65/zzz-lang/zzz"
 )
 
-func Start(userName string, in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+// Start runs the read-eval-print loop until in is exhausted or the user types
+// "exit". Prompts, results and program output all go to out, and builtins that
+// read input share in with the loop. Options, such as zzz.WithEngine, configure
+// the interpreter that runs each line.
+func Start(userName string, in io.Reader, out io.Writer, options ...zzz.Option) {
+	reader := bufio.NewReader(in)
+	options = append([]zzz.Option{zzz.WithStdout(out), zzz.WithStderr(out), zzz.WithStdin(reader)}, options...)
+	interp := zzz.New(options...)
 
 	for {
-		fmt.Printf("@%s>> ", userName)
-		scanned := scanner.Scan()
-		if !scanned {
+		fmt.Fprintf(out, "@%s>> ", userName)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
+		line = strings.TrimRight(line, "\r\n")
 		if line == "exit" {
-			fmt.Println("exiting...")
-			os.Exit(0)
-		}
-
-		l := lexer.New(line)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
-			continue
+			io.WriteString(out, "exiting...\n")
+			return
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluated, err := interp.Eval(line)
 
-		if evaluated != nil {
+		var parseErr *zzz.ParseError
+		var runtimeErr *zzz.RuntimeError
+		switch {
+		case errors.As(err, &parseErr):
+			printParserErrors(out, parseErr.Errors)
+		case errors.As(err, &runtimeErr):
+			io.WriteString(out, runtimeErr.Err.Inspect())
+			io.WriteString(out, "\n")
+		case evaluated != nil:
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
@@ -0,0 +1,57 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/zzz"
+)
+
+func TestStartWritesToOut(t *testing.T) {
+	for _, engine := range []zzz.Engine{zzz.EngineEval, zzz.EngineVM} {
+		in := strings.NewReader("lit x = 2;\nspit(x * 21);\nlen(\"zzz\")\nx + nah\nexit\nspit(1);\n")
+		var out bytes.Buffer
+
+		Start("tester", in, &out, zzz.WithEngine(engine))
+
+		expected := "@tester>> " +
+			"@tester>> 42\n" +
+			"@tester>> 3\n" +
+			"@tester>> ERROR: type mismatch: INTEGER + BOOLEAN\n" +
+			"@tester>> exiting...\n"
+		if out.String() != expected {
+			t.Errorf("%s: wrong output.\nexpected=%q\ngot=%q", engine, expected, out.String())
+		}
+	}
+}
+
+func TestStartEchoesNullButNotStatements(t *testing.T) {
+	for _, engine := range []zzz.Engine{zzz.EngineEval, zzz.EngineVM} {
+		in := strings.NewReader("fr (nah) { 1 };\n[1][5];\nlit x = 1;\nimport \"std/math\";\nspit(x);\n")
+		var out bytes.Buffer
+
+		Start("tester", in, &out, zzz.WithEngine(engine))
+
+		expected := "@tester>> null\n" +
+			"@tester>> null\n" +
+			"@tester>> " +
+			"@tester>> " +
+			"@tester>> 1\n" +
+			"@tester>> "
+		if out.String() != expected {
+			t.Errorf("%s: wrong output.\nexpected=%q\ngot=%q", engine, expected, out.String())
+		}
+	}
+}
+
+func TestStartReportsParserErrors(t *testing.T) {
+	in := strings.NewReader("lit = 5;\n")
+	var out bytes.Buffer
+
+	Start("tester", in, &out)
+
+	if !strings.Contains(out.String(), "\texpected next token to be of type IDENT, got = instead\n") {
+		t.Errorf("parser error not reported. got=%q", out.String())
+	}
+}
@@ -8,6 +8,8 @@ type TokenType string
 type Token struct {
 	Type    TokenType // Type is the category of the token.
 	Literal string    // Literal is the textual representation of the token.
+	Line    int       // Line is the 1-based line the token starts on, or 0 if unknown.
+	Column  int       // Column is the 1-based byte offset of the token within its line.
 }
 
 const (
@@ -23,12 +25,13 @@ const (
 
 	// Operators
 
-	ASSIGN   TokenType = "=" // ASSIGN represents the assignment operator.
-	PLUS     TokenType = "+" // PLUS represents the addition operator.
-	MINUS    TokenType = "-" // MINUS represents the subtraction operator.
-	BANG     TokenType = "!" // BANG represents the logical negation operator.
-	ASTERISK TokenType = "*" // ASTERISK represents the multiplication operator.
-	SLASH    TokenType = "/" // SLASH represents the division operator.
+	ASSIGN   TokenType = "="  // ASSIGN represents the assignment operator.
+	PLUS     TokenType = "+"  // PLUS represents the addition operator.
+	MINUS    TokenType = "-"  // MINUS represents the subtraction operator.
+	BANG     TokenType = "!"  // BANG represents the logical negation operator.
+	ASTERISK TokenType = "*"  // ASTERISK represents the multiplication operator.
+	SLASH    TokenType = "/"  // SLASH represents the division operator.
+	POWER    TokenType = "**" // POWER represents the exponentiation operator.
 
 	LT     TokenType = "<"  // LT represents the less-than comparison operator.
 	GT     TokenType = ">"  // GT represents the greater-than comparison operator.
@@ -54,13 +57,18 @@ const (
 	RETURN   TokenType = "RETURN"   // RETURN represents the 'return' keyword.
 	TRUE     TokenType = "TRUE"     // TRUE represents the 'true' keyword.
 	FALSE    TokenType = "FALSE"    // FALSE represents the 'false' keyword.
+	IMPORT   TokenType = "IMPORT"   // IMPORT represents the 'import' keyword.
+	AS       TokenType = "AS"       // AS represents the 'as' keyword. (for import aliases)
 
-	STRING TokenType = "STRING" // STRING represents string literals.
+	STRING   TokenType = "STRING"   // STRING represents string literals.
+	TEMPLATE TokenType = "TEMPLATE" // TEMPLATE represents string literals with embedded `${...}` expressions.
 
 	LBRACKET TokenType = "[" // LBRACKET represents the left bracket.
 	RBRACKET TokenType = "]" // RBRACKET represents the right bracket.
 
-	COLON TokenType = ":" // COLON represents the colon delimiter. (for hashes)
+	COLON TokenType = ":"  // COLON represents the colon delimiter. (for hashes)
+	DOT   TokenType = "."  // DOT represents the member access operator.
+	ARROW TokenType = "->" // ARROW represents the return type delimiter.
 )
 
 // keywords maps string literals to their corresponding TokenType.
@@ -72,6 +80,8 @@ var keywords = map[string]TokenType{
 	"return": RETURN,
 	"yea":    TRUE,
 	"nah":    FALSE,
+	"import": IMPORT,
+	"as":     AS,
 }
 
 // LookUpIndent returns the TokenType for a given identifier.
@@ -88,3 +98,11 @@ func LookUpIndent(ident string) TokenType {
 func NewToken(tokenType TokenType, ch byte) Token {
 	return Token{Type: tokenType, Literal: string(ch)}
 }
+
+// Comment is a `//` comment, which runs to the end of its line. The lexer
+// skips comments but records them, see lexer.Lexer.Comments.
+type Comment struct {
+	Text   string // the comment, including the leading "//"
+	Line   int
+	Column int
+}
@@ -0,0 +1,104 @@
+package vm
+
+import (
+	"context"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/compiler"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Env is the global state that persists between programs run one after another,
+// like the lines of a REPL session: the compiler's global symbols and the unit
+// holding the constants and global values.
+type Env struct {
+	symbols *compiler.SymbolTable
+	unit    *object.Unit
+}
+
+// NewEnv returns an empty global environment.
+func NewEnv() *Env {
+	return &Env{symbols: compiler.NewSymbolTable(), unit: &object.Unit{}}
+}
+
+// Get returns the global bound to name.
+func (env *Env) Get(name string) (object.Object, bool) {
+	symbol, ok := env.symbols.Resolve(name)
+	if !ok || symbol.Index >= len(env.unit.Globals) || env.unit.Globals[symbol.Index] == nil {
+		return nil, false
+	}
+	return env.unit.Globals[symbol.Index], true
+}
+
+// Set binds name to val as a global.
+func (env *Env) Set(name string, val object.Object) {
+	symbol := env.symbols.Define(name)
+	env.sync(env.symbols.GlobalNames())
+	env.unit.Globals[symbol.Index] = val
+}
+
+// sync grows the globals to match the global symbols defined so far.
+func (env *Env) sync(names []string) {
+	env.unit.Names = names
+	for len(env.unit.Globals) < len(names) {
+		env.unit.Globals = append(env.unit.Globals, nil)
+	}
+}
+
+// EvalContext compiles program and runs it against env, with the same budgets,
+// streams and result as evaluator.EvalContext.
+func EvalContext(ctx context.Context, program *ast.Program, env *Env, opts evaluator.Options) object.Object {
+	comp := compiler.NewWithState(env.symbols, env.unit.Constants)
+	if err := comp.Compile(program); err != nil {
+		return evaluator.NewError("%s", err)
+	}
+
+	bytecode := comp.Bytecode()
+	env.unit.Constants = bytecode.Constants
+	env.sync(bytecode.GlobalNames)
+
+	vm, cancel := newVM(ctx, opts)
+	defer cancel()
+
+	return vm.runUnit(&object.CompiledFunction{Instructions: bytecode.Instructions}, env.unit)
+}
+
+// RunContext runs already compiled bytecode with fresh globals.
+func RunContext(ctx context.Context, bytecode *compiler.Bytecode, opts evaluator.Options) object.Object {
+	vm, cancel := newVM(ctx, opts)
+	defer cancel()
+
+	return vm.runUnit(&object.CompiledFunction{Instructions: bytecode.Instructions}, newUnit(bytecode))
+}
+
+// ApplyContext calls fn, a closure, builtin or evaluator function, with args
+// under the budgets and streams in opts.
+func ApplyContext(ctx context.Context, fn object.Object, args []object.Object, opts evaluator.Options) object.Object {
+	cl, ok := fn.(*object.Closure)
+	if !ok {
+		return evaluator.ApplyContext(ctx, fn, args, opts)
+	}
+
+	vm, cancel := newVM(ctx, opts)
+	defer cancel()
+
+	for _, obj := range append([]object.Object{cl}, args...) {
+		if err := vm.push(obj); err != nil {
+			return err
+		}
+	}
+	floor := len(vm.frames)
+	if err := vm.callClosure(cl, len(args)); err != nil {
+		return err
+	}
+	return vm.run(floor)
+}
+
+func newUnit(bytecode *compiler.Bytecode) *object.Unit {
+	return &object.Unit{
+		Constants: bytecode.Constants,
+		Globals:   make([]object.Object, len(bytecode.GlobalNames)),
+		Names:     bytecode.GlobalNames,
+	}
+}
@@ -0,0 +1,30 @@
+package vm
+
+import (
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Frame is the activation record of one running closure.
+type Frame struct {
+	cl          *object.Closure
+	ip          int
+	basePointer int // stack index of the closure's first local
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: -1, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
+
+// cell holds a local that closures capture. The frame and the closures share
+// the cell, so they all see the value the local has at the time they read it.
+type cell struct {
+	Value object.Object // nil until the local is assigned
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string         { return "cell" }
@@ -0,0 +1,62 @@
+package vm
+
+import (
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// Approximate sizes, in bytes, of a call frame and of one argument slot. They
+// match what the evaluator charges for a call environment.
+const (
+	frameSize = 48
+	slotSize  = 16
+)
+
+// ctxCheckInterval is how many instructions pass between polls of the context.
+const ctxCheckInterval = 1024
+
+// step accounts for one executed instruction and reports whether execution must stop.
+func (vm *VM) step() *object.Error {
+	vm.steps++
+	if vm.opts.MaxSteps > 0 && vm.steps > vm.opts.MaxSteps {
+		return limitError(object.STEP_LIMIT_ERR, "step limit exceeded: %d", vm.opts.MaxSteps)
+	}
+
+	if vm.steps%ctxCheckInterval == 1 {
+		if err := vm.ctx.Err(); err != nil {
+			return limitError(object.CANCELED_ERR, "evaluation canceled: %s", err)
+		}
+	}
+	return nil
+}
+
+// charge accounts for n freshly allocated bytes.
+func (vm *VM) charge(n int64) *object.Error {
+	vm.memory += n
+	if vm.opts.MaxMemory > 0 && vm.memory > vm.opts.MaxMemory {
+		return limitError(object.MEMORY_LIMIT_ERR, "memory limit exceeded: %d bytes", vm.opts.MaxMemory)
+	}
+	return nil
+}
+
+// Reserve implements object.Runtime.
+func (vm *VM) Reserve(n int64) *object.Error {
+	if vm.opts.MaxMemory > 0 && n > vm.opts.MaxMemory-vm.memory {
+		return limitError(object.MEMORY_LIMIT_ERR, "memory limit exceeded: %d bytes", vm.opts.MaxMemory)
+	}
+	return nil
+}
+
+// alloc charges the size of a freshly created object and passes it through.
+func (vm *VM) alloc(obj object.Object) object.Object {
+	if err := vm.charge(evaluator.SizeOf(obj)); err != nil {
+		return err
+	}
+	return obj
+}
+
+func limitError(kind object.ErrorKind, format string, a ...any) *object.Error {
+	err := evaluator.NewError(format, a...)
+	err.Kind = kind
+	return err
+}
@@ -0,0 +1,75 @@
+package vm
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/compiler"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+// importModule returns the module for path, compiling it and running it with
+// its own globals the first time it is requested. Modules are resolved and
+// cached exactly like the evaluator does, so both engines can share a cache.
+func (vm *VM) importModule(path string) object.Object {
+	key, src, err := evaluator.ResolveModule(vm.dir, path)
+	if err != nil {
+		return evaluator.NewError("cannot import %q: %s", path, err)
+	}
+
+	if module, ok := vm.modules.Lookup(key); ok {
+		return module
+	}
+	if module, ok := evaluator.NativeModule(key); ok {
+		vm.modules.Store(key, module)
+		return module
+	}
+
+	for i, importing := range vm.importing {
+		if importing == key {
+			cycle := append(append([]string{}, vm.importing[i:]...), key)
+			return evaluator.ImportCycleError(cycle)
+		}
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return evaluator.NewError("cannot import %q: %s", path, strings.Join(p.Errors(), "; "))
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return evaluator.NewError("cannot import %q: %s", path, err)
+	}
+	bytecode := comp.Bytecode()
+	unit := newUnit(bytecode)
+
+	dir := vm.dir
+	vm.dir = evaluator.ModuleDir(key)
+	vm.importing = append(vm.importing, key)
+
+	result := vm.runUnit(&object.CompiledFunction{Instructions: bytecode.Instructions}, unit)
+
+	vm.importing = vm.importing[:len(vm.importing)-1]
+	vm.dir = dir
+
+	if result != nil && result.Type() == object.ERROR_OBJ {
+		return result
+	}
+
+	env := object.NewEnvironment()
+	for i, name := range unit.Names {
+		if unit.Globals[i] != nil {
+			env.Set(name, unit.Globals[i])
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(key), ".zzz")
+	module := &object.Module{Name: name, Path: key, Env: env}
+	vm.modules.Store(key, module)
+	return module
+}
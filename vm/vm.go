@@ -0,0 +1,592 @@
+// Package vm executes bytecode produced by the compiler package. It shares its
+// object types, builtins and operator semantics with the evaluator, so a program
+// gives the same result on either engine.
+package vm
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/code"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+const (
+	StackSize    = 2048    // initial number of stack slots
+	MaxStackSize = 1 << 20 // the stack grows up to this many slots before overflowing
+)
+
+// VM runs closures on a shared value stack. It is also the object.Runtime handed
+// to builtin functions.
+type VM struct {
+	stack []object.Object
+	sp    int // always points to the next free slot; the top of the stack is stack[sp-1]
+
+	frames []*Frame
+
+	ctx    context.Context
+	opts   evaluator.Options
+	steps  int64
+	memory int64
+	depth  int
+
+	dir       string   // directory of the file being run
+	importing []string // modules currently being run, outermost first
+	modules   *evaluator.ModuleCache
+}
+
+func newVM(ctx context.Context, opts evaluator.Options) (*VM, context.CancelFunc) {
+	cancel := context.CancelFunc(func() {})
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	modules := opts.Modules
+	if modules == nil {
+		modules = evaluator.NewModuleCache()
+	}
+	if opts.Builtins == nil {
+		opts.Builtins = evaluator.NewBuiltins()
+	}
+
+	vm := &VM{
+		stack:   make([]object.Object, StackSize),
+		ctx:     ctx,
+		opts:    opts,
+		dir:     opts.Dir,
+		modules: modules,
+	}
+	return vm, cancel
+}
+
+// runUnit runs the main function of a compiled program or module to completion.
+func (vm *VM) runUnit(main *object.CompiledFunction, unit *object.Unit) object.Object {
+	cl := &object.Closure{Fn: main, Unit: unit}
+	if err := vm.push(cl); err != nil {
+		return err
+	}
+	vm.pushFrame(NewFrame(cl, vm.sp))
+	return vm.run(len(vm.frames) - 1)
+}
+
+// run executes instructions until the frame at index floor returns, and
+// returns its result. Any error object stops execution and is returned as is.
+func (vm *VM) run(floor int) object.Object {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for {
+		if err := vm.step(); err != nil {
+			return err
+		}
+
+		frame := vm.currentFrame()
+		frame.ip++
+		ip = frame.ip
+		ins = frame.Instructions()
+		op = code.Opcode(ins[ip])
+		unit := frame.cl.Unit
+
+		var err object.Object
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			err = vm.push(unit.Constants[constIndex])
+
+		case code.OpPop:
+			vm.pop()
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpPow,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			err = vm.executeBinaryOperation(op)
+
+		case code.OpTrue:
+			err = vm.push(object.TRUE)
+
+		case code.OpFalse:
+			err = vm.push(object.FALSE)
+
+		case code.OpNull:
+			err = vm.push(object.NULL)
+
+		case code.OpBang:
+			err = vm.pushResult(evaluator.Prefix("!", vm.pop()))
+
+		case code.OpMinus:
+			err = vm.pushResult(vm.alloc(evaluator.Prefix("-", vm.pop())))
+
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip = pos - 1
+
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+
+			if !evaluator.Truthy(vm.pop()) {
+				frame.ip = pos - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			unit.Globals[globalIndex] = vm.popValue()
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+			err = vm.pushResult(vm.global(unit, int(globalIndex)))
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			vm.stack[frame.basePointer+int(localIndex)] = vm.popValue()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.pushVariable(vm.stack[frame.basePointer+int(localIndex)], ins[ip+2:])
+
+		case code.OpMakeCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+
+			slot := &vm.stack[frame.basePointer+int(localIndex)]
+			if chargeErr := vm.charge(slotSize); chargeErr != nil {
+				err = chargeErr
+			} else {
+				*slot = &cell{Value: *slot}
+			}
+
+		case code.OpGetCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+
+			var c *cell
+			if c, err = cellAt(vm.stack[frame.basePointer+int(localIndex)]); err == nil {
+				err = vm.pushVariable(c.Value, ins[ip+2:])
+			}
+
+		case code.OpSetCell:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+
+			var c *cell
+			if c, err = cellAt(vm.stack[frame.basePointer+int(localIndex)]); err == nil {
+				c.Value = vm.popValue()
+			}
+
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+
+			var c *cell
+			if c, err = cellAt(frame.cl.Free[freeIndex]); err == nil {
+				err = vm.pushVariable(c.Value, ins[ip+2:])
+			}
+
+		case code.OpGetFreeCell:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.push(frame.cl.Free[freeIndex])
+
+		case code.OpJumpIfSet:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+
+			if vm.stack[vm.sp-1] != nil {
+				frame.ip = pos - 1
+			} else {
+				vm.pop()
+			}
+
+		case code.OpCurrentClosure:
+			err = vm.push(frame.cl)
+
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+			err = vm.pushResult(vm.alloc(array))
+
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+
+			hash := vm.buildHash(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+			err = vm.pushResult(vm.alloc(hash))
+
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evaluator.Index(left, index))
+
+		case code.OpConcat:
+			count := int(code.ReadUint16(ins[ip+1:]))
+			frame.ip += 2
+
+			str := evaluator.Concat(vm.stack[vm.sp-count : vm.sp])
+			vm.sp = vm.sp - count
+			err = vm.pushResult(vm.alloc(str))
+
+		case code.OpSlice:
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(vm.alloc(evaluator.Slice(left, start, end)))
+
+		case code.OpMember:
+			nameIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+
+			name := unit.Constants[nameIndex].(*object.String).Value
+			err = vm.pushResult(evaluator.Member(vm.pop(), name))
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			frame.ip += 1
+			err = vm.executeCall(int(numArgs))
+
+		case code.OpReturnValue, code.OpReturn:
+			var returnValue object.Object
+			if op == code.OpReturnValue {
+				returnValue = vm.pop()
+			}
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+			if len(vm.frames) == floor {
+				return returnValue
+			}
+
+			vm.depth--
+			if returnValue == nil {
+				returnValue = object.NULL
+			}
+			err = vm.push(returnValue)
+
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			frame.ip += 3
+			err = vm.pushClosure(unit, int(constIndex), int(numFree))
+
+		case code.OpImport:
+			pathIndex := code.ReadUint16(ins[ip+1:])
+			frame.ip += 2
+
+			path := unit.Constants[pathIndex].(*object.String).Value
+			err = vm.pushResult(vm.importModule(path))
+
+		default:
+			def, _ := code.Lookup(byte(op))
+			return evaluator.NewError("unknown opcode %v", def)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// global returns the global at index, falling back to the builtin of the same
+// name while the global is unset.
+func (vm *VM) global(unit *object.Unit, index int) object.Object {
+	if value := unit.Globals[index]; value != nil {
+		return value
+	}
+
+	name := unit.Names[index]
+	if builtin, ok := vm.opts.Builtins[name]; ok {
+		return builtin
+	}
+	return evaluator.NewError("undefined identifier: " + name)
+}
+
+func (vm *VM) executeBinaryOperation(op code.Opcode) object.Object {
+	right := vm.pop()
+	left := vm.pop()
+
+	// Integer arithmetic is by far the most common case, handle it inline.
+	if l, ok := left.(*object.Integer); ok {
+		if r, ok := right.(*object.Integer); ok {
+			switch op {
+			case code.OpAdd:
+				return vm.pushResult(vm.alloc(&object.Integer{Value: l.Value + r.Value}))
+			case code.OpSub:
+				return vm.pushResult(vm.alloc(&object.Integer{Value: l.Value - r.Value}))
+			case code.OpMul:
+				return vm.pushResult(vm.alloc(&object.Integer{Value: l.Value * r.Value}))
+			case code.OpGreaterThan:
+				return vm.push(object.NativeBool(l.Value > r.Value))
+			case code.OpLessThan:
+				return vm.push(object.NativeBool(l.Value < r.Value))
+			case code.OpEqual:
+				return vm.push(object.NativeBool(l.Value == r.Value))
+			case code.OpNotEqual:
+				return vm.push(object.NativeBool(l.Value != r.Value))
+			}
+		}
+	}
+
+	return vm.pushResult(vm.alloc(evaluator.Infix(operators[op], left, right)))
+}
+
+// operators maps binary opcodes back to the source operators the evaluator implements.
+var operators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpPow:         "**",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+	copy(elements, vm.stack[startIndex:endIndex])
+	return &object.Array{Elements: elements}
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) object.Object {
+	hash := object.NewHash((endIndex - startIndex) / 2)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := object.AsKey(key)
+		if !ok {
+			return evaluator.NewError("unusable as hash key: %s", key.Type())
+		}
+
+		hash.Set(hashKey, value)
+	}
+
+	return hash
+}
+
+func (vm *VM) executeCall(numArgs int) object.Object {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		args := make([]object.Object, numArgs)
+		copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+		vm.sp = vm.sp - numArgs - 1
+
+		return vm.pushResult(vm.alloc(callee.Fn(vm, args...)))
+	case *object.Function:
+		// Functions created by the evaluator, e.g. taken from a module it
+		// imported, run on the evaluator.
+		args := make([]object.Object, numArgs)
+		copy(args, vm.stack[vm.sp-numArgs:vm.sp])
+		vm.sp = vm.sp - numArgs - 1
+
+		return vm.pushResult(evaluator.ApplyContext(vm.ctx, callee, args, vm.opts))
+	default:
+		return evaluator.NewError("not a function: %s", callee.Type())
+	}
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) object.Object {
+	if numArgs != cl.Fn.NumParameters {
+		return evaluator.NewError("wrong number of arguments. got=%d, want=%d", numArgs, cl.Fn.NumParameters)
+	}
+
+	vm.depth++
+	if limit := vm.opts.Depth(); vm.depth > limit {
+		return limitError(object.DEPTH_LIMIT_ERR, "call depth limit exceeded: %d", limit)
+	}
+	if err := vm.charge(frameSize + int64(numArgs)*slotSize); err != nil {
+		return err
+	}
+
+	basePointer := vm.sp - numArgs
+	if err := vm.reserve(basePointer + cl.Fn.NumLocals); err != nil {
+		return err
+	}
+	// Clear the locals that are not arguments, the slots may hold stale values.
+	for i := vm.sp; i < basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+
+	vm.pushFrame(NewFrame(cl, basePointer))
+	vm.sp = basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+func (vm *VM) pushClosure(unit *object.Unit, constIndex, numFree int) object.Object {
+	function, ok := unit.Constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return evaluator.NewError("not a function: %+v", unit.Constants[constIndex])
+	}
+
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: function, Free: free, Unit: unit})
+}
+
+// pushVariable pushes the value of a local, cell or free variable, read by the
+// instruction before rest. The value is nil if the variable's `lit` has not run
+// yet, which the compiler checks with the OpJumpIfSet that follows such reads.
+// Any other instruction would fail on nil, so the read is an error instead.
+func (vm *VM) pushVariable(value object.Object, rest code.Instructions) object.Object {
+	if value == nil && (len(rest) == 0 || code.Opcode(rest[0]) != code.OpJumpIfSet) {
+		return evaluator.NewError("variable read before it was assigned")
+	}
+	return vm.push(value)
+}
+
+// cellAt returns obj as the cell of a captured local, or an error if the
+// bytecode put something else there.
+func cellAt(obj object.Object) (*cell, object.Object) {
+	c, ok := obj.(*cell)
+	if !ok {
+		return nil, evaluator.NewError("not a cell: %T", obj)
+	}
+	return c, nil
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[len(vm.frames)-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames = append(vm.frames, f)
+}
+
+func (vm *VM) popFrame() *Frame {
+	f := vm.currentFrame()
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	return f
+}
+
+// reserve makes sure the stack has at least n slots.
+func (vm *VM) reserve(n int) object.Object {
+	if n <= len(vm.stack) {
+		return nil
+	}
+	if n > MaxStackSize {
+		return evaluator.NewError("stack overflow")
+	}
+
+	size := len(vm.stack) * 2
+	for size < n {
+		size *= 2
+	}
+	stack := make([]object.Object, min(size, MaxStackSize))
+	copy(stack, vm.stack[:vm.sp])
+	vm.stack = stack
+	return nil
+}
+
+func (vm *VM) push(o object.Object) object.Object {
+	if err := vm.reserve(vm.sp + 1); err != nil {
+		return err
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+// pushResult pushes the result of an operation, or returns it if it is an error.
+func (vm *VM) pushResult(o object.Object) object.Object {
+	if o != nil && o.Type() == object.ERROR_OBJ {
+		return o
+	}
+	return vm.push(o)
+}
+
+// popValue pops a value to bind to a name. Calls like spit(...) produce no
+// value, and the name is bound to null instead.
+func (vm *VM) popValue() object.Object {
+	if o := vm.pop(); o != nil {
+		return o
+	}
+	return object.NULL
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+func (vm *VM) Call(fn object.Object, args ...object.Object) object.Object {
+	sp, frames, depth := vm.sp, len(vm.frames), vm.depth
+	result := vm.call(fn, args)
+	// An error leaves the frames of the failed call behind.
+	vm.sp, vm.frames, vm.depth = sp, vm.frames[:frames], depth
+	if result == nil {
+		return object.NULL
+	}
+	return result
+}
+
+// call runs fn on top of the stack until it returns.
+func (vm *VM) call(fn object.Object, args []object.Object) object.Object {
+	if err := vm.push(fn); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return err
+		}
+	}
+
+	floor := len(vm.frames)
+	if err := vm.executeCall(len(args)); err != nil {
+		return err
+	}
+	if len(vm.frames) == floor {
+		// A builtin or evaluator function, its result is already pushed.
+		return vm.pop()
+	}
+	return vm.run(floor)
+}
+
+func (vm *VM) Random() *rand.Rand {
+	if vm.opts.Random == nil {
+		vm.opts.Random = evaluator.NewRandom()
+	}
+	return vm.opts.Random
+}
+
+func (vm *VM) Stdout() io.Writer {
+	if vm.opts.Stdout != nil {
+		return vm.opts.Stdout
+	}
+	return os.Stdout
+}
+
+func (vm *VM) Stderr() io.Writer {
+	if vm.opts.Stderr != nil {
+		return vm.opts.Stderr
+	}
+	return os.Stderr
+}
+
+func (vm *VM) Stdin() io.Reader {
+	if vm.opts.Stdin != nil {
+		return vm.opts.Stdin
+	}
+	return os.Stdin
+}
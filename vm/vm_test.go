@@ -0,0 +1,135 @@
+package vm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/compiler"
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+func TestRecursiveClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lit fib = fun(n) { fr (n < 2) { n } lowkey { fib(n - 1) + fib(n - 2) } }; fib(15)`, "610"},
+		{`lit wrapper = fun() { lit inner = fun(x) { fr (x == 0) { 0 } lowkey { inner(x - 1) } }; inner(3) }; wrapper()`, "0"},
+		{`lit adder = fun(a) { fun(b) { fun(c) { a + b + c } } }; adder(1)(2)(3)`, "6"},
+		{`lit deep = fun(n) { fr (n == 0) { 0 } lowkey { 1 + deep(n - 1) } }; deep(5000)`, "5000"},
+		{`fun() { fr (nah) { lit x = 1; } x }()`, "ERROR: undefined identifier: x"},
+	}
+
+	for _, tt := range tests {
+		result := EvalContext(context.Background(), parse(tt.input), NewEnv(), evaluator.Options{})
+		if result == nil || result.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. got=%v, expected=%s", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestEnvPersistsBetweenPrograms(t *testing.T) {
+	env := NewEnv()
+	env.Set("base", &object.Integer{Value: 10})
+
+	inputs := []string{
+		"lit add = fun(x) { base + x };",
+		"lit base = 20;",
+		"add(1)",
+	}
+
+	var result object.Object
+	for _, input := range inputs {
+		result = EvalContext(context.Background(), parse(input), env, evaluator.Options{})
+	}
+	if result.Inspect() != "21" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	if add, ok := env.Get("add"); !ok || add.Type() != object.FUNCTION_OBJ {
+		t.Errorf("Get did not return the global function. got=%v", add)
+	}
+	if _, ok := env.Get("missing"); ok {
+		t.Errorf("Get found an undefined global")
+	}
+}
+
+func TestApplyContext(t *testing.T) {
+	env := NewEnv()
+	EvalContext(context.Background(), parse("lit mul = fun(a, b) { a * b };"), env, evaluator.Options{})
+	mul, _ := env.Get("mul")
+
+	result := ApplyContext(context.Background(), mul, []object.Object{&object.Integer{Value: 6}, &object.Integer{Value: 7}}, evaluator.Options{})
+	if result.Inspect() != "42" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+
+	result = ApplyContext(context.Background(), mul, nil, evaluator.Options{})
+	if result.Inspect() != "ERROR: wrong number of arguments. got=0, want=2" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`lit greet = fun(name) { "hi " + name }; greet("vm")`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	result := RunContext(context.Background(), comp.Bytecode(), evaluator.Options{})
+	if result.Inspect() != "hi vm" {
+		t.Errorf("wrong result. got=%s", result.Inspect())
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	input := "lit f = fun(x) { f(x + 1) }; f(0);"
+	// A depth limit above what the stack holds, so the stack runs out first.
+	result := EvalContext(context.Background(), parse(input), NewEnv(), evaluator.Options{MaxDepth: MaxStackSize})
+
+	errObj, ok := result.(*object.Error)
+	if !ok || errObj.Message != "stack overflow" {
+		t.Errorf("expected stack overflow. got=%v", result)
+	}
+}
+
+// FuzzRunCompiled runs mutated compiled programs. The loader must reject every
+// program the VM cannot run safely, so running one never panics.
+func FuzzRunCompiled(f *testing.F) {
+	seeds := []string{
+		`lit adder = fun(a) { fun(b) { a + b } }; adder(1)(2)`,
+		`lit f = fun(n) { fr (n < 2) { n } lowkey { f(n - 1) + f(n - 2) } }; [f(5), {"a": 1}["a"], "ab"[0:1]]`,
+		`lit g = fun() { lit h = fun() { x }; lit x = 1; h() }; g()`,
+	}
+	for _, input := range seeds {
+		comp := compiler.New()
+		if err := comp.Compile(parse(input)); err != nil {
+			f.Fatalf("compiler error: %s", err)
+		}
+		data, err := comp.Bytecode().MarshalBinary()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bytecode := &compiler.Bytecode{}
+		if err := bytecode.UnmarshalBinary(data); err != nil {
+			return
+		}
+		opts := evaluator.Options{MaxSteps: 10000, MaxMemory: 1 << 20, Stdout: io.Discard, Dir: t.TempDir()}
+		RunContext(context.Background(), bytecode, opts)
+	})
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
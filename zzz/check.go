@@ -0,0 +1,34 @@
+package zzz
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/checker"
+)
+
+// Check parses src and reports the problems checker.Check finds in it.
+func Check(src string) ([]checker.Diagnostic, error) {
+	program, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return checker.Check(program), nil
+}
+
+// CheckFile checks the program at path like Check.
+func CheckFile(path string) ([]checker.Diagnostic, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isCompiled(src) {
+		return nil, fmt.Errorf("%s: cannot check a compiled program", path)
+	}
+
+	diagnostics, err := Check(string(src))
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = path
+	}
+	return diagnostics, err
+}
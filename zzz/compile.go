@@ -0,0 +1,56 @@
+package zzz
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/compiler"
+	"github.com/amirhesham65/zzz-lang/optimizer"
+)
+
+// Compile parses src, optimizes it and compiles it to bytecode for the VM
+// engine.
+func Compile(src string) (*compiler.Bytecode, error) {
+	program, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(optimizer.Optimize(program)); err != nil {
+		return nil, err
+	}
+	return comp.Bytecode(), nil
+}
+
+// CompileFile compiles the program at path like Compile. A program already
+// compiled by `zzz build` is loaded as is.
+func CompileFile(path string) (*compiler.Bytecode, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isCompiled(src) {
+		return loadCompiled(path, src)
+	}
+
+	bytecode, err := Compile(string(src))
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = path
+	}
+	return bytecode, err
+}
+
+// isCompiled reports whether data holds a compiled program rather than source.
+func isCompiled(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(compiler.Magic))
+}
+
+func loadCompiled(path string, data []byte) (*compiler.Bytecode, error) {
+	bytecode := &compiler.Bytecode{}
+	if err := bytecode.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bytecode, nil
+}
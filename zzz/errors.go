@@ -0,0 +1,35 @@
+package zzz
+
+import (
+	"strings"
+
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+// ParseError reports that a program could not be parsed.
+type ParseError struct {
+	File   string // empty when the program did not come from a file
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	msg := "parse error: " + strings.Join(e.Errors, "; ")
+	if e.File != "" {
+		return e.File + ": " + msg
+	}
+	return msg
+}
+
+// RuntimeError reports that evaluation produced an error object.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Message
+}
+
+// IsLimit reports whether evaluation stopped because a budget ran out.
+func (e *RuntimeError) IsLimit() bool {
+	return e.Err.IsLimit()
+}
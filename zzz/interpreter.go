@@ -0,0 +1,296 @@
+// Package zzz embeds the ZZZ language in Go host programs.
+//
+// An Interpreter owns its global environment, builtins and I/O streams, so any
+// number of them can run side by side in one process:
+//
+//	interp := zzz.New(zzz.WithStdout(&buf))
+//	interp.Run(`lit add = fun(a, b) { a + b };`)
+//	sum, err := interp.Call("add", &object.Integer{Value: 1}, &object.Integer{Value: 2})
+package zzz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/amirhesham65/zzz-lang/evaluator"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/object"
+	"github.com/amirhesham65/zzz-lang/optimizer"
+	"github.com/amirhesham65/zzz-lang/parser"
+	"github.com/amirhesham65/zzz-lang/vm"
+)
+
+// Interpreter runs ZZZ programs against a persistent global environment.
+// It is not safe for concurrent use.
+type Interpreter struct {
+	engine   Engine
+	optimize bool
+	env      *object.Environment // globals of the tree-walking engine
+	vmEnv    *vm.Env             // globals of the bytecode engine
+	opts     evaluator.Options
+}
+
+// Engine selects how an Interpreter executes programs.
+type Engine int
+
+const (
+	EngineEval Engine = iota // walk the syntax tree with the evaluator package
+	EngineVM                 // compile to bytecode and run it on the vm package
+)
+
+// ParseEngine returns the engine named "eval" or "vm".
+func ParseEngine(name string) (Engine, error) {
+	switch name {
+	case "eval":
+		return EngineEval, nil
+	case "vm":
+		return EngineVM, nil
+	}
+	return 0, fmt.Errorf("unknown engine %q, want eval or vm", name)
+}
+
+func (e Engine) String() string {
+	if e == EngineVM {
+		return "vm"
+	}
+	return "eval"
+}
+
+// Option configures an Interpreter created by New.
+type Option func(*Interpreter)
+
+// WithStdout sets the writer that builtins like `spit` print to.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.opts.Stdout = w }
+}
+
+// WithStderr sets the writer builtins report diagnostics to.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.opts.Stderr = w }
+}
+
+// WithStdin sets the reader builtins read input from.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.opts.Stdin = r }
+}
+
+// WithEngine selects the engine that runs programs. The default is EngineEval.
+func WithEngine(e Engine) Option {
+	return func(i *Interpreter) { i.engine = e }
+}
+
+// WithOptimize turns the optimizer package's rewrites of programs before they
+// run on or off. They are on by default.
+func WithOptimize(on bool) Option {
+	return func(i *Interpreter) { i.optimize = on }
+}
+
+// WithMaxSteps bounds the number of AST nodes, or VM instructions, a single Run
+// or Call may evaluate.
+func WithMaxSteps(n int64) Option {
+	return func(i *Interpreter) { i.opts.MaxSteps = n }
+}
+
+// WithMaxMemory bounds the approximate bytes a single Run or Call may allocate.
+func WithMaxMemory(n int64) Option {
+	return func(i *Interpreter) { i.opts.MaxMemory = n }
+}
+
+// WithMaxDepth bounds the nesting of user function calls. Without it, calls
+// nest at most evaluator.DefaultMaxDepth deep.
+func WithMaxDepth(n int) Option {
+	return func(i *Interpreter) { i.opts.MaxDepth = n }
+}
+
+// WithDir sets the directory relative imports are resolved against when running
+// source that does not come from a file.
+func WithDir(dir string) Option {
+	return func(i *Interpreter) { i.opts.Dir = dir }
+}
+
+// WithTimeout bounds the wall-clock time of a single Run or Call.
+func WithTimeout(d time.Duration) Option {
+	return func(i *Interpreter) { i.opts.Timeout = d }
+}
+
+// WithSeed seeds the generator std/random draws from, so that every run of a
+// program gets the same numbers. Without it the seed is unpredictable.
+func WithSeed(seed int64) Option {
+	return func(i *Interpreter) { i.opts.Random = rand.New(rand.NewSource(seed)) }
+}
+
+// New creates an Interpreter with an empty global environment and its own copy
+// of the default builtins.
+func New(options ...Option) *Interpreter {
+	i := &Interpreter{
+		optimize: true,
+		env:      object.NewEnvironment(),
+		vmEnv:    vm.NewEnv(),
+		opts: evaluator.Options{
+			Builtins: evaluator.NewBuiltins(),
+			Stdout:   os.Stdout,
+			Stderr:   os.Stderr,
+			Stdin:    os.Stdin,
+			Random:   evaluator.NewRandom(),
+			Modules:  evaluator.NewModuleCache(),
+		},
+	}
+
+	for _, option := range options {
+		option(i)
+	}
+
+	return i
+}
+
+// Run parses and evaluates src in the interpreter's global environment and
+// returns the value of the last statement.
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.RunContext(context.Background(), src)
+}
+
+// RunContext is like Run but stops evaluation once ctx is done.
+func (i *Interpreter) RunContext(ctx context.Context, src string) (object.Object, error) {
+	return i.run(ctx, src, i.opts)
+}
+
+// RunFile reads the program at path and runs it like Run. Relative imports in
+// the program are resolved against the file's directory.
+//
+// A compiled program written by `zzz build` always runs on the VM engine, with
+// globals of its own rather than the interpreter's.
+func (i *Interpreter) RunFile(path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := i.opts
+	opts.Dir = filepath.Join(opts.Dir, filepath.Dir(path))
+	if filepath.IsAbs(path) {
+		opts.Dir = filepath.Dir(path)
+	}
+
+	if isCompiled(src) {
+		bytecode, err := loadCompiled(path, src)
+		if err != nil {
+			return nil, err
+		}
+		return result(vm.RunContext(context.Background(), bytecode, opts))
+	}
+
+	obj, err := i.run(context.Background(), string(src), opts)
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = path
+	}
+	return obj, err
+}
+
+// Eval runs src like Run, except that its value is nil when the last statement
+// produces none, as `lit`, `import` and calls like spit(...) do. The REPL uses
+// it to echo only values, null among them.
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	obj, err := i.eval(context.Background(), src, i.opts)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+	return result(obj)
+}
+
+func (i *Interpreter) run(ctx context.Context, src string, opts evaluator.Options) (object.Object, error) {
+	obj, err := i.eval(ctx, src, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result(obj)
+}
+
+// eval parses and evaluates src, returning the engine's result as is.
+func (i *Interpreter) eval(ctx context.Context, src string, opts evaluator.Options) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+	if i.optimize {
+		optimizer.Optimize(program)
+		opts.Literals = optimizer.Literals(program)
+	}
+
+	if i.engine == EngineVM {
+		return vm.EvalContext(ctx, program, i.vmEnv, opts), nil
+	}
+	return evaluator.EvalContext(ctx, program, i.env, opts), nil
+}
+
+// Set binds name to value in the global environment.
+func (i *Interpreter) Set(name string, value object.Object) {
+	if i.engine == EngineVM {
+		i.vmEnv.Set(name, value)
+		return
+	}
+	i.env.Set(name, value)
+}
+
+// Get returns the global binding for name.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	if i.engine == EngineVM {
+		return i.vmEnv.Get(name)
+	}
+	return i.env.Get(name)
+}
+
+// SetBuiltin installs fn as a builtin of this interpreter only.
+func (i *Interpreter) SetBuiltin(name string, fn object.BuiltinFunction) {
+	i.opts.Builtins[name] = &object.Builtin{Fn: fn}
+}
+
+// RegisterFunc installs an arbitrary Go function as a builtin of this
+// interpreter. Arguments and results are converted with object.ToGo and
+// object.FromGo, and a non-nil error result surfaces as a runtime error.
+func (i *Interpreter) RegisterFunc(name string, fn any) error {
+	builtin, err := object.WrapFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.opts.Builtins[name] = builtin
+	return nil
+}
+
+// Call invokes the global function or builtin named fnName with args.
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext is like Call but stops evaluation once ctx is done.
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.Get(fnName)
+	if !ok {
+		builtin, ok := i.opts.Builtins[fnName]
+		if !ok {
+			return nil, fmt.Errorf("undefined function: %s", fnName)
+		}
+		fn = builtin
+	}
+
+	if i.engine == EngineVM {
+		return result(vm.ApplyContext(ctx, fn, args, i.opts))
+	}
+	return result(evaluator.ApplyContext(ctx, fn, args, i.opts))
+}
+
+// result turns an evaluation result into the host-facing (value, error) pair.
+func result(obj object.Object) (object.Object, error) {
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+	if errObj, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return obj, nil
+}
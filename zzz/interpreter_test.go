@@ -0,0 +1,369 @@
+package zzz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/amirhesham65/zzz-lang/compiler"
+	"github.com/amirhesham65/zzz-lang/object"
+)
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out))
+
+	result, err := interp.Run(`lit name = "zzz"; spit("hi " + name); len(name);`)
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testIntegerObject(t, result, 3)
+
+	if out.String() != "hi zzz\n" {
+		t.Errorf("wrong stdout. got=%q", out.String())
+	}
+}
+
+func TestRunKeepsGlobals(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Run("lit x = 40;"); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	result, err := interp.Run("x + 2")
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.zzz")
+	if err := os.WriteFile(path, []byte("lit double = fun(x) { x * 2 }; double(21);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New().RunFile(path)
+	if err != nil {
+		t.Fatalf("RunFile returned error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+}
+
+func TestRunErrors(t *testing.T) {
+	interp := New(WithMaxSteps(100))
+
+	_, err := interp.Run("lit = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Errorf("expected ParseError. got=%T (%v)", err, err)
+	}
+
+	_, err = interp.Run(`5 + "five"`)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.IsLimit() {
+		t.Errorf("expected non-limit RuntimeError. got=%T (%v)", err, err)
+	}
+
+	_, err = interp.Run("lit f = fun() { f() }; f();")
+	if !errors.As(err, &runtimeErr) || !runtimeErr.IsLimit() {
+		t.Errorf("expected limit RuntimeError. got=%T (%v)", err, err)
+	}
+}
+
+func TestSetGetCall(t *testing.T) {
+	interp := New()
+	interp.Set("base", &object.Integer{Value: 10})
+
+	if _, err := interp.Run("lit add = fun(a, b) { base + a + b };"); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+
+	result, err := interp.Call("add", &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err != nil {
+		t.Fatalf("Call returned error: %s", err)
+	}
+	testIntegerObject(t, result, 13)
+
+	result, err = interp.Call("len", &object.String{Value: "four"})
+	if err != nil {
+		t.Fatalf("Call returned error: %s", err)
+	}
+	testIntegerObject(t, result, 4)
+
+	if _, err := interp.Call("add", &object.Integer{Value: 1}); err == nil {
+		t.Errorf("expected arity error from Call")
+	}
+	if _, err := interp.Call("missing"); err == nil {
+		t.Errorf("expected error calling undefined function")
+	}
+
+	if _, ok := interp.Get("add"); !ok {
+		t.Errorf("Get did not find global add")
+	}
+}
+
+func TestInterpretersAreIsolated(t *testing.T) {
+	var outA, outB bytes.Buffer
+	a := New(WithStdout(&outA))
+	b := New(WithStdout(&outB))
+
+	a.SetBuiltin("answer", func(rt object.Runtime, args ...object.Object) object.Object {
+		return &object.Integer{Value: 42}
+	})
+
+	if _, err := a.Run(`lit x = answer(); spit("a");`); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	if _, err := b.Run(`answer()`); err == nil {
+		t.Errorf("builtin registered on one interpreter leaked into another")
+	}
+	if _, err := b.Run(`spit("b"); x`); err == nil {
+		t.Errorf("global defined in one interpreter leaked into another")
+	}
+
+	if outA.String() != "a\n" || outB.String() != "b\n" {
+		t.Errorf("output crossed interpreters. a=%q, b=%q", outA.String(), outB.String())
+	}
+}
+
+func TestBuiltinCallsFunctions(t *testing.T) {
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		interp := New(WithEngine(engine))
+		interp.SetBuiltin("twice", func(rt object.Runtime, args ...object.Object) object.Object {
+			return rt.Call(args[0], rt.Call(args[0], args[1]))
+		})
+
+		result, err := interp.Run(`lit inc = fun(x) { x + 1 }; twice(inc, 1) + len(twice(rest, [1, 2, 3]))`)
+		if err != nil {
+			t.Fatalf("engine %v: Run returned error: %s", engine, err)
+		}
+		testIntegerObject(t, result, 4)
+
+		if _, err := interp.Run(`twice(fun(x) { x + "s" }, 1)`); err == nil {
+			t.Errorf("engine %v: error in a called function was lost", engine)
+		}
+		result, err = interp.Run(`twice(inc, 40)`)
+		if err != nil {
+			t.Fatalf("engine %v: Run after an error returned error: %s", engine, err)
+		}
+		testIntegerObject(t, result, 42)
+	}
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, expected=%d", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func TestRegisterFunc(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out))
+
+	err := interp.RegisterFunc("greet", func(rt object.Runtime, p struct {
+		Name string `zzz:"name"`
+	}) string {
+		fmt.Fprintln(rt.Stdout(), "greeting", p.Name)
+		return "hi " + p.Name
+	})
+	if err != nil {
+		t.Fatalf("RegisterFunc returned error: %s", err)
+	}
+	interp.RegisterFunc("parse", strconv.Atoi)
+
+	result, err := interp.Run(`greet({"name": "Amir"})`)
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	if result.Inspect() != "hi Amir" || out.String() != "greeting Amir\n" {
+		t.Errorf("wrong result. got=%q, stdout=%q", result.Inspect(), out.String())
+	}
+
+	result, err = interp.Run(`parse("41") + 1`)
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+
+	if _, err := interp.Run(`parse("zzz")`); err == nil || !strings.Contains(err.Error(), "invalid syntax") {
+		t.Errorf("expected Go error to surface. got=%v", err)
+	}
+	if err := interp.RegisterFunc("bad", "not a function"); err == nil {
+		t.Errorf("expected error registering a non-function")
+	}
+}
+
+func TestVMEngine(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithEngine(EngineVM), WithStdout(&out), WithMaxDepth(50))
+	interp.Set("base", &object.Integer{Value: 10})
+
+	if _, err := interp.Run(`lit add = fun(a, b) { base + a + b }; spit("ready");`); err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	result, err := interp.Run("add(1, 2)")
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testIntegerObject(t, result, 13)
+
+	result, err = interp.Call("add", &object.Integer{Value: 2}, &object.Integer{Value: 3})
+	if err != nil {
+		t.Fatalf("Call returned error: %s", err)
+	}
+	testIntegerObject(t, result, 15)
+
+	if result, err := interp.Run("lit unused = 1;"); result != object.NULL || err != nil {
+		t.Errorf("expected null from a lit. got=%v, %v", result, err)
+	}
+	if result, err := interp.Eval("lit unused = 1;"); result != nil || err != nil {
+		t.Errorf("expected no value from a lit. got=%v, %v", result, err)
+	}
+	if result, err := interp.Eval("[1][5]"); result != object.NULL || err != nil {
+		t.Errorf("expected null from an index out of range. got=%v, %v", result, err)
+	}
+
+	var runtimeErr *RuntimeError
+	if _, err := interp.Run("lit f = fun() { f() }; f();"); !errors.As(err, &runtimeErr) || !runtimeErr.IsLimit() {
+		t.Errorf("expected limit RuntimeError. got=%T (%v)", err, err)
+	}
+
+	if out.String() != "ready\n" {
+		t.Errorf("wrong stdout. got=%q", out.String())
+	}
+}
+
+func TestWithOptimize(t *testing.T) {
+	// Folded to a single literal, the sum fits in a step budget that the
+	// unoptimized expression exceeds.
+	const src = "1 + 2 + 3 + 4 + 5 + 6 + 7 + 8"
+
+	result, err := New(WithMaxSteps(10)).Run(src)
+	if err != nil {
+		t.Fatalf("Run returned error: %s", err)
+	}
+	testIntegerObject(t, result, 36)
+
+	var runtimeErr *RuntimeError
+	if _, err := New(WithMaxSteps(10), WithOptimize(false)).Run(src); !errors.As(err, &runtimeErr) || !runtimeErr.IsLimit() {
+		t.Errorf("expected limit RuntimeError without the optimizer. got=%T (%v)", err, err)
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	const src = `import "std/random"; [random.randInt(1, 1000), random.rand(), random.shuffle([1, 2, 3, 4, 5])]`
+
+	for _, engine := range []Engine{EngineEval, EngineVM} {
+		a, b := New(WithEngine(engine), WithSeed(7)), New(WithEngine(engine), WithSeed(7))
+		for run := 0; run < 2; run++ {
+			resultA, err := a.Run(src)
+			if err != nil {
+				t.Fatalf("%s: Run returned error: %s", engine, err)
+			}
+			resultB, err := b.Run(src)
+			if err != nil {
+				t.Fatalf("%s: Run returned error: %s", engine, err)
+			}
+			if resultA.Inspect() != resultB.Inspect() {
+				t.Errorf("%s: run %d differs with the same seed. %s != %s", engine, run, resultA.Inspect(), resultB.Inspect())
+			}
+		}
+	}
+
+	// The generator carries on across runs instead of starting over.
+	interp := New(WithSeed(7))
+	first, _ := interp.Run(src)
+	second, _ := interp.Run(src)
+	if first.Inspect() == second.Inspect() {
+		t.Errorf("consecutive runs drew the same numbers: %s", first.Inspect())
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	for _, name := range []string{"eval", "vm"} {
+		engine, err := ParseEngine(name)
+		if err != nil || engine.String() != name {
+			t.Errorf("ParseEngine(%q) = %s, %v", name, engine, err)
+		}
+	}
+	if _, err := ParseEngine("jit"); err == nil {
+		t.Errorf("expected error for unknown engine")
+	}
+}
+
+func TestRunCompiledFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "lib.zzz"), []byte(`lit triple = fun(x) { x * 3 };`), 0o644)
+
+	bytecode, err := Compile(`import "lib.zzz"; lib.triple(14)`)
+	if err != nil {
+		t.Fatalf("Compile returned error: %s", err)
+	}
+	data, _ := bytecode.MarshalBinary()
+	path := filepath.Join(dir, "main.zzzc")
+	os.WriteFile(path, data, 0o644)
+
+	result, err := New().RunFile(path)
+	if err != nil {
+		t.Fatalf("RunFile returned error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+
+	data[len(compiler.Magic)+1]++
+	os.WriteFile(path, data, 0o644)
+	var versionErr *compiler.VersionError
+	if _, err := New().RunFile(path); !errors.As(err, &versionErr) {
+		t.Errorf("expected VersionError. got=%v", err)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.zzz")
+	os.WriteFile(path, []byte("lit x = 1;\nspitt(x);\n"), 0o644)
+
+	diagnostics, err := CheckFile(path)
+	if err != nil {
+		t.Fatalf("CheckFile returned error: %s", err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].String() != "2:1: error: undefined identifier: spitt" {
+		t.Errorf("wrong diagnostics. got=%v", diagnostics)
+	}
+
+	os.WriteFile(path, []byte("lit = 1;"), 0o644)
+	var parseErr *ParseError
+	if _, err := CheckFile(path); !errors.As(err, &parseErr) || parseErr.File != path {
+		t.Errorf("expected ParseError for %s. got=%T (%v)", path, err, err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.zzz")
+	os.WriteFile(path, []byte("lit x = 1;"), 0o644)
+
+	program, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %s", err)
+	}
+	if program.String() != "lit x = 1;" {
+		t.Errorf("wrong program. got=%q", program.String())
+	}
+
+	os.WriteFile(path, []byte("lit = 1;"), 0o644)
+	var parseErr *ParseError
+	if _, err := ParseFile(path); !errors.As(err, &parseErr) || parseErr.File != path {
+		t.Errorf("expected ParseError for %s. got=%T (%v)", path, err, err)
+	}
+}
@@ -0,0 +1,37 @@
+package zzz
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/amirhesham65/zzz-lang/ast"
+	"github.com/amirhesham65/zzz-lang/lexer"
+	"github.com/amirhesham65/zzz-lang/parser"
+)
+
+// Parse parses src into a syntax tree.
+func Parse(src string) (*ast.Program, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Errors: p.Errors()}
+	}
+	return program, nil
+}
+
+// ParseFile parses the program at path like Parse.
+func ParseFile(path string) (*ast.Program, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isCompiled(src) {
+		return nil, fmt.Errorf("%s: cannot parse a compiled program", path)
+	}
+
+	program, err := Parse(string(src))
+	if perr, ok := err.(*ParseError); ok {
+		perr.File = path
+	}
+	return program, err
+}